@@ -8,6 +8,43 @@ type Action struct {
 	SetEnv      string
 	SetEnvValue string
 	SetColor    string
+	UnsetEnv    string
+	// SetAlias defines a shell alias that runs SetAliasValue
+	SetAlias      string
+	SetAliasValue string
+	// OnEnter and OnLeave are shell commands to run when Path becomes active
+	// or inactive
+	OnEnter string
+	OnLeave string
+	// Warning is a message to show once when Path becomes active
+	Warning string
+	// ListVar is the list env var for AddPath, which defaults to PATH
+	ListVar string
+	// Append adds AddPath to the end of the list instead of the front
+	Append bool
+	// Expand is set if SetEnvValue can contain variable references
+	Expand bool
+	// Checker is the name of the checker that returned the action
+	Checker string
+	// Source describes where the action came from, like "file:line"
+	Source string
+}
+
+// Origin describes where the action came from for display, like
+// "DotEnvCheck ~/proj/.envy:3". The shorten function is applied to the Source.
+func (a Action) Origin(shorten func(string) string) string {
+	if a.Source == "" {
+		return a.Checker
+	}
+	return a.Checker + " " + shorten(a.Source)
+}
+
+// PathList returns the name of the list env var for AddPath.
+func (a Action) PathList() string {
+	if a.ListVar == "" {
+		return "PATH"
+	}
+	return a.ListVar
 }
 
 // List is a slice of Action structs with a sort.Interface predefined.
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/wojas/envy/checkers"
+	"github.com/wojas/envy/paths"
+	"github.com/wojas/envy/trust"
+)
+
+// envyFileFor returns the absolute path of the .envy file for a directory
+// argument, which defaults to the current working directory. An explicit
+// path to a file is also accepted.
+func envyFileFor(arg string) string {
+	if arg == "" {
+		arg = "."
+	}
+	p, err := filepath.Abs(arg)
+	if err != nil {
+		log.Fatalf("Invalid path %s: %v", arg, err)
+	}
+	if paths.IsFile(p) {
+		return p
+	}
+	return filepath.Join(p, checkers.EnvyFile)
+}
+
+// displayPath shortens a path for display.
+func displayPath(p string) string {
+	home, err := paths.HomeDir()
+	if err != nil {
+		return p
+	}
+	return paths.Shorten{Home: home}.Do(p)
+}
+
+// runAllow marks the .envy file in a directory as trusted with its current
+// contents.
+func runAllow(dir string) {
+	p := envyFileFor(dir)
+	contents, err := ioutil.ReadFile(p)
+	if err != nil {
+		log.Fatalf("Cannot allow %s: %v", displayPath(p), err)
+	}
+	if err := trust.Default().Allow(p, contents); err != nil {
+		log.Fatalf("Cannot allow %s: %v", displayPath(p), err)
+	}
+	log.Printf("allowed %s", displayPath(p))
+}
+
+// runDeny removes the trust for the .envy file in a directory.
+func runDeny(dir string) {
+	p := envyFileFor(dir)
+	if err := trust.Default().Deny(p); err != nil {
+		log.Fatalf("Cannot deny %s: %v", displayPath(p), err)
+	}
+	log.Printf("denied %s", displayPath(p))
+}
+
+// runTrust handles the 'trust' subcommands.
+func runTrust(args []string) {
+	if len(args) == 0 || args[0] != "list" {
+		log.Fatalf("Usage: envy trust list")
+	}
+
+	store := trust.Default()
+	entries, err := store.List()
+	if err != nil {
+		log.Fatalf("Cannot read trust store %s: %v", store.Dir, err)
+	}
+	for _, e := range entries {
+		status := trust.Unknown
+		if contents, err := ioutil.ReadFile(e.Path); err == nil {
+			status = store.Status(e.Path, contents)
+		} else if os.IsNotExist(err) {
+			fmt.Printf("%-9s %s\n", "missing", displayPath(e.Path))
+			continue
+		}
+		fmt.Printf("%-9s %s\n", status, displayPath(e.Path))
+	}
+}
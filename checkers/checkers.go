@@ -4,17 +4,23 @@ import (
 	"path/filepath"
 
 	"github.com/wojas/envy/action"
+	"github.com/wojas/envy/config"
 	"github.com/wojas/envy/paths"
 )
 
-// AllCheckers is a list of all Checker interfaces to use.
-var AllCheckers = []Checker{
-	BinCheck{"bin"},
-	BinCheck{"node_modules/.bin"},
-	BinCheck{".venv/bin"},
-	DotEnvCheck{".envy"},
-	GoPathCheck{},
-	GitRootCheck{},
+// All returns a list of all Checker interfaces to use with a config.
+func All(conf *config.Config) []Checker {
+	return []Checker{
+		BinCheck{"bin"},
+		BinCheck{"node_modules/.bin"},
+		VirtualEnvCheck{conf.VenvDirs},
+		DotEnvCheck{EnvyFile},
+		GoPathCheck{},
+		GitRootCheck{},
+		HgRootCheck{},
+		JJRootCheck{},
+		FossilRootCheck{},
+	}
 }
 
 // Checker is the interface shared by functions that check for Actions to take
@@ -1,20 +1,27 @@
 package checkers
 
 import (
+	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 
-	"github.com/subosito/gotenv" // TODO: find a better one
+	"github.com/wojas/envy/dotenv"
 	"github.com/wojas/envy/env"
+	"github.com/wojas/envy/shell"
+	"github.com/wojas/envy/trust"
 
 	"github.com/wojas/envy/action"
 	"github.com/wojas/envy/paths"
 )
 
-// DotEnvCheck checks for a .env file and loads the variables defined there
+// EnvyFile is the name of the files loaded by DotEnvCheck.
+const EnvyFile = ".envy"
+
+// DotEnvCheck checks for a .env file and loads the variables defined there,
+// if the user allowed the file with its current contents.
 type DotEnvCheck struct {
 	RelPath string
 }
@@ -26,66 +33,118 @@ func (c DotEnvCheck) Check(path string) (actions action.List) {
 		return
 	}
 
-	f, err := os.Open(p)
+	contents, err := ioutil.ReadFile(p)
 	if err != nil {
 		log.Printf("Warning: could not open %s: %v", p, err)
 		return
 	}
-	defer f.Close()
 
-	// TODO: better parser that does not stop on error
-	// TODO: ignore PATH and ENVY_* vars
-	dotenv := gotenv.Parse(f)
-	keys := make([]string, 0, len(dotenv))
-	for k := range dotenv {
-		keys = append(keys, k)
+	// Never apply a file the user did not explicitly allow, because it could
+	// have been put there by a freshly cloned repository. The warning is only
+	// shown when the directory is entered, not on every prompt.
+	var warning string
+	switch trust.Default().Status(p, contents) {
+	case trust.Modified:
+		warning = fmt.Sprintf("%s changed since it was allowed, run 'envy allow %s' to load it",
+			shorten(p), shorten(path))
+	case trust.Unknown:
+		warning = fmt.Sprintf("%s is not allowed, run 'envy allow %s' to load it",
+			shorten(p), shorten(path))
+	}
+	if warning != "" {
+		actions = append(actions, action.Action{
+			Path:    path,
+			Warning: warning,
+		})
+		return
 	}
-	sort.Strings(keys)
 
-	for _, k := range keys {
-		v := dotenv[k]
-		if strings.HasPrefix(k, "ENVY_") {
-			actions = handleEnvyVar(actions, path, k, v)
-		} else {
-			actions = append(actions, action.Action{
-				Path:        path,
-				Priority:    -1,
-				SetEnv:      k,
-				SetEnvValue: v,
-			})
-		}
+	// Apply all valid lines, even if other lines contain errors
+	// TODO: ignore PATH, paths are added with ENVY_EXTEND_PATH
+	entries, errs := dotenv.Parse(p, contents)
+	for _, e := range errs {
+		log.Printf("%s:%d: %s", shorten(e.File), e.Line, e.Msg)
+	}
+
+	// ENVY_* vars are converted by handleEnvyVar after variable expansion
+	for _, e := range entries {
+		actions = append(actions, action.Action{
+			Path:        path,
+			Priority:    -1,
+			SetEnv:      e.Key,
+			SetEnvValue: e.Value,
+			Expand:      !e.Literal,
+			Source:      fmt.Sprintf("%s:%d", e.File, e.Line),
+		})
 	}
 	return
 }
 
-func handleEnvyVar(actions action.List, path, k, v string) action.List {
-	// TODO: make paths absolute
+// handleEnvyVar converts an ENVY_* var into the actions it describes.
+func handleEnvyVar(actions action.List, a action.Action) action.List {
+	path, k, v := a.Path, a.SetEnv, a.SetEnvValue
 	switch k {
 	case "ENVY_EXTEND_PATH":
 		for _, p := range env.ReversePaths(filepath.SplitList(v)) {
 			actions = append(actions, action.Action{
 				Path:     path,
 				Priority: -1,
-				AddPath:  p,
+				AddPath:  resolvePath(a, p),
+				Source:   a.Source,
 			})
 		}
 	case "ENVY_GOROOT":
+		root := resolvePath(a, v)
 		actions = append(actions, action.Action{
 			Path:     path,
 			Priority: -1,
-			AddPath:  filepath.Join(v, "bin"),
+			AddPath:  filepath.Join(root, "bin"),
+			Source:   a.Source,
 		}, action.Action{
 			Path:        path,
 			Priority:    -1,
 			SetEnv:      "GOROOT",
-			SetEnvValue: v,
+			SetEnvValue: root,
+			Source:      a.Source,
 		})
 	case "ENVY_PYTHONROOT":
 		actions = append(actions, action.Action{
 			Path:     path,
 			Priority: -1,
-			AddPath:  filepath.Join(v, "bin"),
+			AddPath:  filepath.Join(resolvePath(a, v), "bin"),
+			Source:   a.Source,
 		})
+	case "ENVY_UNSET":
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if !shell.ValidEnvVar(name) {
+				log.Printf("%s: %s contains an invalid var name %q", shorten(a.Source), k, name)
+				continue
+			}
+			actions = append(actions, action.Action{
+				Path:     path,
+				Priority: -1,
+				UnsetEnv: name,
+				Source:   a.Source,
+			})
+		}
+	case "ENVY_ON_ENTER", "ENVY_ON_LEAVE":
+		// These run arbitrary commands, which is only acceptable because we
+		// only get here for .envy files the user explicitly allowed.
+		act := action.Action{
+			Path:     path,
+			Priority: -1,
+			Source:   a.Source,
+		}
+		if k == "ENVY_ON_ENTER" {
+			act.OnEnter = v
+		} else {
+			act.OnLeave = v
+		}
+		actions = append(actions, act)
 	case "ENVY_COLOR":
 		// Handled in main
 		actions = append(actions, action.Action{
@@ -93,9 +152,74 @@ func handleEnvyVar(actions action.List, path, k, v string) action.List {
 			Priority:    -1,
 			SetEnv:      k,
 			SetEnvValue: v,
+			Source:      a.Source,
 		})
 	default:
-		log.Printf("%s not supported in env files", k)
+		if name := strings.TrimPrefix(k, "ENVY_PREPEND_"); name != k {
+			return addToList(actions, a, name, false)
+		}
+		if name := strings.TrimPrefix(k, "ENVY_APPEND_"); name != k {
+			return addToList(actions, a, name, true)
+		}
+		if name := strings.TrimPrefix(k, "ENVY_ALIAS_"); name != k {
+			if name == "" {
+				log.Printf("%s: %s is missing the name of the alias", shorten(a.Source), k)
+				return actions
+			}
+			return append(actions, action.Action{
+				Path:          path,
+				Priority:      -1,
+				SetAlias:      name,
+				SetAliasValue: v,
+				Source:        a.Source,
+			})
+		}
+		log.Printf("%s: %s not supported in env files", shorten(a.Source), k)
 	}
 	return actions
 }
+
+// addToList handles ENVY_PREPEND_* and ENVY_APPEND_* vars, which add the ':'
+// separated paths to a list env var like PYTHONPATH or MANPATH, in the order
+// given.
+func addToList(actions action.List, a action.Action, name string, appendPaths bool) action.List {
+	if name == "" {
+		log.Printf("%s: %s is missing the name of a var", shorten(a.Source), a.SetEnv)
+		return actions
+	}
+	list := filepath.SplitList(a.SetEnvValue)
+	if !appendPaths {
+		list = env.ReversePaths(list) // Every path is added to the front
+	}
+	for _, p := range list {
+		actions = append(actions, action.Action{
+			Path:     a.Path,
+			Priority: -1,
+			AddPath:  resolvePath(a, p),
+			ListVar:  name,
+			Append:   appendPaths,
+			Source:   a.Source,
+		})
+	}
+	return actions
+}
+
+// resolvePath returns the absolute path for a path in an ENVY_* var, which can
+// be relative to the directory of the .envy file or start with '~'. It warns
+// if the path does not exist.
+func resolvePath(a action.Action, p string) string {
+	resolved := paths.Resolve(p, a.Path)
+	if _, err := os.Stat(resolved); err != nil {
+		log.Printf("%s: %s: %s does not exist", shorten(a.Source), a.SetEnv, shorten(resolved))
+	}
+	return resolved
+}
+
+// shorten replaces the home dir with '~' for display.
+func shorten(p string) string {
+	home, err := paths.HomeDir()
+	if err != nil {
+		return p
+	}
+	return paths.Shorten{Home: home}.Do(p)
+}
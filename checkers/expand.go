@@ -0,0 +1,85 @@
+package checkers
+
+import (
+	"log"
+	"strings"
+
+	"github.com/wojas/envy/action"
+	"github.com/wojas/envy/dotenv"
+	"github.com/wojas/envy/env"
+)
+
+// Expand expands variable references in the actions that allow it, like the
+// values in .envy files, and converts the ENVY_* vars to the actions they
+// describe. The actions must be sorted from shallow to deep paths.
+//
+// References are resolved in this order:
+//
+//   - ENVY_DIR, the directory that contains the .envy file;
+//   - _ENVY_* vars set by other checkers for the same or a shallower path,
+//     like _ENVY_GITROOT;
+//   - vars set or unset earlier in the same file or in shallower paths;
+//   - the environment before envy made any changes, as returned by base.
+func Expand(actions action.List, base dotenv.Lookup) (expanded action.List) {
+	defined := make(map[string]env.Value)
+	for _, a := range actions {
+		k, v := a.SetEnv, a.SetEnvValue
+		if k != "" && a.Expand {
+			var undefined []string
+			v, undefined = dotenv.Expand(v, lookupFor(a, actions, defined, base))
+			for _, name := range undefined {
+				log.Printf("%s: undefined variable %s", shorten(a.Source), name)
+			}
+			a.SetEnvValue = v
+			a.Expand = false
+		}
+
+		if strings.HasPrefix(k, "ENVY_") {
+			n := len(expanded)
+			expanded = handleEnvyVar(expanded, a)
+			for i := n; i < len(expanded); i++ {
+				expanded[i].Checker = a.Checker
+			}
+			continue
+		}
+		if k != "" {
+			defined[k] = env.Value{Val: v}
+		}
+		if a.UnsetEnv != "" {
+			defined[a.UnsetEnv] = env.Value{Unset: true}
+		}
+		expanded = append(expanded, a)
+	}
+	return expanded
+}
+
+// lookupFor returns the Lookup to expand the value of an action.
+func lookupFor(a action.Action, actions action.List, defined map[string]env.Value, base dotenv.Lookup) dotenv.Lookup {
+	return func(key string) (string, bool) {
+		if key == "ENVY_DIR" {
+			return a.Path, true
+		}
+		if strings.HasPrefix(key, "_ENVY_") {
+			if v, ok := builtin(key, a.Path, actions); ok {
+				return v, true
+			}
+		}
+		if v, ok := defined[key]; ok {
+			return v.Val, !v.Unset
+		}
+		return base(key)
+	}
+}
+
+// builtin returns the value of a _ENVY_* var set by a checker for the deepest
+// path that is not deeper than the given path. Actions for the same path are
+// included, even if they come later in the list.
+func builtin(key, path string, actions action.List) (val string, found bool) {
+	for _, b := range actions {
+		if b.SetEnv != key || b.Expand || len(b.Path) > len(path) {
+			continue
+		}
+		val, found = b.SetEnvValue, true
+	}
+	return val, found
+}
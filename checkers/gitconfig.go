@@ -0,0 +1,203 @@
+package checkers
+
+import (
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// gitConfig contains the values of a git config file by key, like
+// "remote.origin.url". Section and key names are lowercase, because these are
+// case insensitive, but subsection names are kept as is.
+type gitConfig map[string][]string
+
+// readGitConfig reads the config file in a git dir. Includes are not
+// supported. It returns an empty config if the file cannot be read.
+func readGitConfig(git string) gitConfig {
+	contents, err := ioutil.ReadFile(filepath.Join(git, "config"))
+	if err != nil {
+		return make(gitConfig)
+	}
+	return parseGitConfig(contents)
+}
+
+// parseGitConfig parses the contents of a git config file. Invalid lines are
+// ignored.
+func parseGitConfig(contents []byte) gitConfig {
+	conf := make(gitConfig)
+	var section string
+	for _, line := range strings.Split(string(contents), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if line[0] == '[' {
+			end := strings.LastIndexByte(line, ']')
+			if end < 0 {
+				section = ""
+				continue
+			}
+			section = parseGitSection(line[1:end])
+			continue
+		}
+		if section == "" {
+			continue
+		}
+
+		// A key without a value means true
+		key, value := line, "true"
+		if idx := strings.IndexByte(line, '='); idx >= 0 {
+			key, value = line[:idx], parseGitValue(line[idx+1:])
+		}
+		key = section + "." + strings.ToLower(strings.TrimSpace(key))
+		conf[key] = append(conf[key], value)
+	}
+	return conf
+}
+
+// parseGitSection parses a section header without the brackets, like
+// `remote "origin"`, or the deprecated form `branch.main`.
+func parseGitSection(s string) string {
+	if idx := strings.IndexByte(s, '"'); idx >= 0 {
+		name := strings.ToLower(strings.TrimSpace(s[:idx]))
+		sub := strings.TrimSuffix(s[idx+1:], `"`)
+		sub = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(sub)
+		return name + "." + sub
+	}
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// parseGitValue parses a value, which can be partially quoted, contain
+// escapes and end in a comment.
+func parseGitValue(s string) string {
+	var b strings.Builder
+	quoted := false
+	s = strings.TrimSpace(s)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(s[i])
+			}
+		case (c == '#' || c == ';') && !quoted:
+			return strings.TrimSpace(b.String())
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// Get returns the last value for a key, which is the one git uses.
+func (c gitConfig) Get(key string) string {
+	values := c[key]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// Remotes returns the names of all remotes with a URL.
+func (c gitConfig) Remotes() []string {
+	var remotes []string
+	for key := range c {
+		if strings.HasPrefix(key, "remote.") && strings.HasSuffix(key, ".url") {
+			remotes = append(remotes, strings.TrimSuffix(strings.TrimPrefix(key, "remote."), ".url"))
+		}
+	}
+	return remotes
+}
+
+// DefaultRemote returns the remote git uses by default for a branch: the
+// remote configured for the branch, otherwise origin, otherwise the only
+// remote.
+func (c gitConfig) DefaultRemote(branch string) string {
+	if branch != "" {
+		if remote := c.Get("branch." + branch + ".remote"); remote != "" && remote != "." {
+			return remote
+		}
+	}
+	if c.Get("remote.origin.url") != "" {
+		return "origin"
+	}
+	if remotes := c.Remotes(); len(remotes) == 1 {
+		return remotes[0]
+	}
+	return ""
+}
+
+// Upstream returns the upstream of a branch, like "origin/main", or an empty
+// string if none is configured. For a local upstream branch, it returns the
+// name of that branch.
+func (c gitConfig) Upstream(branch string) string {
+	remote := c.Get("branch." + branch + ".remote")
+	merge := c.Get("branch." + branch + ".merge")
+	if remote == "" || merge == "" {
+		return ""
+	}
+	merge = strings.TrimPrefix(merge, "refs/heads/")
+	if remote == "." {
+		return merge
+	}
+	return remote + "/" + merge
+}
+
+// parseGitRemoteURL returns the host, owner and repo name for a remote URL,
+// like "github.com", "wojas" and "envy". It supports URLs with a scheme, like
+// https:// and ssh://, and the scp-like syntax "git@github.com:wojas/envy.git".
+// For nested groups, the owner contains all but the last part of the path.
+// It returns empty strings for local paths.
+func parseGitRemoteURL(remoteURL string) (host, owner, repo string) {
+	var p string
+	if strings.Contains(remoteURL, "://") {
+		u, err := url.Parse(remoteURL)
+		if err != nil || u.Scheme == "file" {
+			return "", "", ""
+		}
+		host, p = u.Hostname(), u.Path
+	} else {
+		// scp-like syntax, which is only recognized if there is no slash
+		// before the first colon
+		idx := strings.IndexByte(remoteURL, ':')
+		if idx < 0 || strings.Contains(remoteURL[:idx], "/") {
+			return "", "", ""
+		}
+		host, p = remoteURL[:idx], remoteURL[idx+1:]
+		if at := strings.LastIndexByte(host, '@'); at >= 0 {
+			host = host[at+1:]
+		}
+	}
+
+	p = strings.TrimSuffix(strings.Trim(p, "/"), ".git")
+	idx := strings.LastIndexByte(p, '/')
+	if host == "" || idx < 0 {
+		return host, "", ""
+	}
+	return strings.ToLower(host), p[:idx], p[idx+1:]
+}
+
+// redactGitURL removes the user info from a remote URL, so that a password
+// or token does not end up in the environment. Tokens are often passed as the
+// user name, so that is removed too. A URL that cannot be parsed is dropped.
+func redactGitURL(remoteURL string) string {
+	if !strings.Contains(remoteURL, "://") {
+		return remoteURL
+	}
+	u, err := url.Parse(remoteURL)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+	return u.String()
+}
@@ -1,16 +1,19 @@
 package checkers
 
 import (
+	"bufio"
 	"bytes"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/wojas/envy/action"
 	"github.com/wojas/envy/paths"
 )
 
-// GitRootCheck checks if a path is the root of a git checkout.
+// GitRootCheck checks if a path is the root of a git checkout. It reads the
+// git files directly instead of running git, which would be too slow.
 type GitRootCheck struct{}
 
 // Check implements the Checker interface.
@@ -36,28 +39,99 @@ func (c GitRootCheck) Check(path string) (actions action.List) {
 		return
 	}
 
-	actions = append(actions, action.Action{
-		Path:        path,
-		SetEnv:      "_ENVY_GITROOT",
-		SetEnvValue: path,
-	})
+	return varActions(path, append(vcsVarNames, gitVarNames...), gitVars(path, git))
+}
+
+// gitVarNames lists the git specific vars set by GitRootCheck. Vars that do
+// not apply are unset, so that the vars of a repository never mix with the
+// ones of a parent repository, like the superproject of a submodule.
+var gitVarNames = []string{
+	"_ENVY_GITROOT",
+	"_ENVY_COMMIT",
+	"_ENVY_GIT_OPERATION",
+	"_ENVY_GIT_REMOTE",
+	"_ENVY_GIT_REMOTE_URL",
+	"_ENVY_GIT_HOST",
+	"_ENVY_GIT_OWNER",
+	"_ENVY_GIT_REPO",
+	"_ENVY_GIT_UPSTREAM",
+	"_ENVY_GIT_TYPE",
+	"_ENVY_GIT_MAIN_WORKTREE",
+	"_ENVY_GIT_SUPERPROJECT",
+}
+
+// gitVars returns the values of the vars for a checkout at path with the
+// given git dir.
+func gitVars(path, git string) map[string]string {
+	vars := map[string]string{
+		"_ENVY_VCS":     "git",
+		"_ENVY_VCSROOT": path,
+		"_ENVY_GITROOT": path,
+	}
+
+	op := gitOperation(git)
+	vars["_ENVY_GIT_OPERATION"] = op
+
+	// A linked worktree has its own git dir for HEAD and such, but shares
+	// the common dir with the main worktree. The git dir of a submodule is
+	// stored in the git dir of its superproject.
+	common := gitCommonDir(git)
+	var superproject string
+	if common == git {
+		superproject = gitSuperproject(git)
+	}
+	switch {
+	case common != git:
+		vars["_ENVY_GIT_TYPE"] = "worktree"
+		vars["_ENVY_GIT_MAIN_WORKTREE"] = gitWorkTree(common)
+	case superproject != "":
+		vars["_ENVY_GIT_TYPE"] = "submodule"
+		vars["_ENVY_GIT_SUPERPROJECT"] = superproject
+	default:
+		vars["_ENVY_GIT_TYPE"] = "repository"
+	}
 
-	ref, err := ioutil.ReadFile(filepath.Join(git, "HEAD"))
+	head, err := ioutil.ReadFile(filepath.Join(git, "HEAD"))
 	if err != nil {
-		return
+		return vars
 	}
-	branch := parseGitRef(ref)
-	if branch == "" {
-		return
+	ref, commit := parseGitHead(head)
+	if ref != "" {
+		commit = resolveGitRef(git, common, ref)
+	} else if op == "rebase" {
+		// HEAD is detached during a rebase, but we know the branch
+		ref = gitRebaseHeadName(git)
 	}
 
-	actions = append(actions, action.Action{
-		Path:        path,
-		SetEnv:      "_ENVY_BRANCH",
-		SetEnvValue: branch,
-	})
+	switch {
+	case ref != "":
+		vars["_ENVY_BRANCH"] = strings.TrimPrefix(ref, "refs/heads/")
+	case len(commit) >= 7:
+		vars["_ENVY_BRANCH"] = commit[:7] // detached head, short commit hash
+	}
+	vars["_ENVY_COMMIT"] = commit
 
-	return
+	// Remotes are configured in the common dir, which contains the config
+	// shared by all worktrees.
+	var branch string
+	if strings.HasPrefix(ref, "refs/heads/") {
+		branch = strings.TrimPrefix(ref, "refs/heads/")
+	}
+	conf := readGitConfig(common)
+	if remote := conf.DefaultRemote(branch); remote != "" {
+		remoteURL := conf.Get("remote." + remote + ".url")
+		vars["_ENVY_GIT_REMOTE"] = remote
+		vars["_ENVY_GIT_REMOTE_URL"] = redactGitURL(remoteURL)
+		if host, owner, repo := parseGitRemoteURL(remoteURL); repo != "" {
+			vars["_ENVY_GIT_HOST"] = host
+			vars["_ENVY_GIT_OWNER"] = owner
+			vars["_ENVY_GIT_REPO"] = repo
+		}
+	}
+	if branch != "" {
+		vars["_ENVY_GIT_UPSTREAM"] = conf.Upstream(branch)
+	}
+	return vars
 }
 
 func parseGitRedirect(path string, contents []byte) string {
@@ -81,17 +155,168 @@ func parseGitRedirect(path string, contents []byte) string {
 	return git
 }
 
-func parseGitRef(ref []byte) string {
-	if !bytes.HasPrefix(ref, []byte("ref: ")) {
-		return string(ref[:7]) // detached head, commit hash
+// gitCommonDir returns the dir with the refs and config that are shared by
+// all worktrees. For a linked worktree, the git dir only contains files like
+// HEAD, and a commondir file that points to the git dir of the main worktree.
+func gitCommonDir(git string) string {
+	contents, err := ioutil.ReadFile(filepath.Join(git, "commondir"))
+	if err != nil {
+		return git
+	}
+	common := strings.TrimSpace(string(contents))
+	if common == "" {
+		return git
+	}
+	if !filepath.IsAbs(common) {
+		common = filepath.Join(git, common)
+	}
+	return common
+}
+
+// gitWorkTree returns the root of the working tree for a git dir. This is
+// core.worktree if configured, which is the case for submodules, otherwise
+// the parent of a .git dir. It returns an empty string for a bare repository.
+func gitWorkTree(git string) string {
+	if wt := readGitConfig(git).Get("core.worktree"); wt != "" {
+		if !filepath.IsAbs(wt) {
+			wt = filepath.Join(git, wt)
+		}
+		return filepath.Clean(wt)
+	}
+	if filepath.Base(git) == ".git" {
+		return filepath.Dir(git)
+	}
+	return ""
+}
+
+// gitSuperproject returns the root of the superproject for the git dir of a
+// submodule, which is stored in the git dir of the superproject, like
+// "/repo/.git/modules/sub". For a nested submodule it returns the submodule
+// that contains it. The name of a submodule can contain "/modules/" as well,
+// so we look for the deepest parent that is a git dir.
+func gitSuperproject(git string) string {
+	p := filepath.ToSlash(git)
+	for idx := strings.LastIndex(p, "/modules/"); idx > 0; idx = strings.LastIndex(p[:idx], "/modules/") {
+		parent := filepath.FromSlash(p[:idx])
+		if paths.IsFile(filepath.Join(parent, "HEAD")) {
+			return gitWorkTree(parent)
+		}
+	}
+	return ""
+}
+
+// parseGitHead parses the contents of HEAD, which either refers to a branch,
+// like "ref: refs/heads/main", or contains a commit hash for a detached head.
+func parseGitHead(head []byte) (ref, commit string) {
+	s := strings.TrimSpace(string(head))
+	if strings.HasPrefix(s, "ref: ") {
+		return strings.TrimSpace(s[5:]), ""
 	}
-	if idx := bytes.IndexByte(ref, '\n'); idx >= 0 {
-		ref = ref[:idx]
+	if isGitHash(s) {
+		return "", s
 	}
-	idx := bytes.LastIndexByte(ref, '/')
-	if idx < 0 {
+	return "", ""
+}
+
+// resolveGitRef returns the commit hash of a ref, like "refs/heads/main".
+// Refs are stored as loose files, or in the packed-refs file. It returns an
+// empty string for a branch without commits.
+func resolveGitRef(git, common, ref string) string {
+	for i := 0; i < 5; i++ { // Limit the depth of symbolic refs
+		// Most refs are in the common dir, but some are per worktree
+		contents, err := ioutil.ReadFile(filepath.Join(common, filepath.FromSlash(ref)))
+		if err != nil && git != common {
+			contents, err = ioutil.ReadFile(filepath.Join(git, filepath.FromSlash(ref)))
+		}
+		if err != nil {
+			return packedGitRef(common, ref)
+		}
+		next, commit := parseGitHead(contents)
+		if next == "" {
+			return commit
+		}
+		ref = next
+	}
+	return ""
+}
+
+// packedGitRef looks up a ref in the packed-refs file, which has a line with
+// "<hash> <ref>" for every ref. Lines starting with '^' contain the commit
+// for the annotated tag on the previous line, and '#' starts a comment.
+func packedGitRef(common, ref string) string {
+	f, err := os.Open(filepath.Join(common, "packed-refs"))
+	if err != nil {
 		return ""
 	}
-	branch := string(ref[idx+1:])
-	return branch
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '#' || line[0] == '^' {
+			continue
+		}
+		idx := strings.IndexByte(line, ' ')
+		if idx < 0 {
+			continue
+		}
+		if line[idx+1:] == ref && isGitHash(line[:idx]) {
+			return line[:idx]
+		}
+	}
+	return ""
+}
+
+// gitOperation returns the operation in progress in a git dir, like
+// "rebase" or "merge", based on the same files as the git prompt script.
+func gitOperation(git string) string {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(git, name))
+		return err == nil
+	}
+	switch {
+	case exists("rebase-merge"):
+		return "rebase"
+	case exists("rebase-apply"):
+		if exists("rebase-apply/applying") {
+			return "am"
+		}
+		return "rebase"
+	case exists("MERGE_HEAD"):
+		return "merge"
+	case exists("CHERRY_PICK_HEAD"):
+		return "cherry-pick"
+	case exists("REVERT_HEAD"):
+		return "revert"
+	case exists("BISECT_LOG"):
+		return "bisect"
+	}
+	return ""
+}
+
+// gitRebaseHeadName returns the ref of the branch that is being rebased.
+func gitRebaseHeadName(git string) string {
+	for _, dir := range []string{"rebase-merge", "rebase-apply"} {
+		contents, err := ioutil.ReadFile(filepath.Join(git, dir, "head-name"))
+		if err != nil {
+			continue
+		}
+		if ref := strings.TrimSpace(string(contents)); strings.HasPrefix(ref, "refs/") {
+			return ref
+		}
+	}
+	return ""
+}
+
+// isGitHash checks if s is a full SHA-1 or SHA-256 commit hash.
+func isGitHash(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
 }
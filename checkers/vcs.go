@@ -0,0 +1,111 @@
+package checkers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wojas/envy/action"
+	"github.com/wojas/envy/paths"
+)
+
+// vcsVarNames lists the vars set by the checkers for all version control
+// systems, so that a nested repository overrides all of them, even if the
+// parent repository uses a different system.
+var vcsVarNames = []string{
+	"_ENVY_VCS",
+	"_ENVY_VCSROOT",
+	"_ENVY_BRANCH",
+	"_ENVY_BOOKMARK",
+}
+
+// varActions returns actions to set the given vars for a path. Vars without
+// a value are unset.
+func varActions(path string, names []string, vars map[string]string) (actions action.List) {
+	for _, k := range names {
+		a := action.Action{Path: path}
+		if v := vars[k]; v != "" {
+			a.SetEnv, a.SetEnvValue = k, v
+		} else {
+			a.UnsetEnv = k
+		}
+		actions = append(actions, a)
+	}
+	return
+}
+
+// readFirstLine returns the first line of a file, or an empty string if it
+// cannot be read.
+func readFirstLine(p string) string {
+	contents, err := ioutil.ReadFile(p)
+	if err != nil {
+		return ""
+	}
+	line := string(contents)
+	if idx := strings.IndexByte(line, '\n'); idx >= 0 {
+		line = line[:idx]
+	}
+	return strings.TrimSpace(line)
+}
+
+// HgRootCheck checks if a path is the root of a Mercurial checkout.
+type HgRootCheck struct{}
+
+// Check implements the Checker interface.
+func (c HgRootCheck) Check(path string) action.List {
+	hg := filepath.Join(path, ".hg")
+	if !paths.IsDir(hg) {
+		return nil
+	}
+
+	// Without a branch file, the checkout is on the default branch
+	branch := readFirstLine(filepath.Join(hg, "branch"))
+	if branch == "" {
+		branch = "default"
+	}
+	return varActions(path, vcsVarNames, map[string]string{
+		"_ENVY_VCS":      "hg",
+		"_ENVY_VCSROOT":  path,
+		"_ENVY_BRANCH":   branch,
+		"_ENVY_BOOKMARK": readFirstLine(filepath.Join(hg, "bookmarks.current")),
+	})
+}
+
+// JJRootCheck checks if a path is the root of a Jujutsu workspace.
+// Jujutsu has no current branch, and the bookmarks are stored in a format we
+// cannot read cheaply, so these are not set.
+type JJRootCheck struct{}
+
+// Check implements the Checker interface.
+func (c JJRootCheck) Check(path string) action.List {
+	if !paths.IsDir(filepath.Join(path, ".jj")) {
+		return nil
+	}
+
+	// A workspace colocated with a git checkout overrides _ENVY_VCS, because
+	// it runs after GitRootCheck, but leaves the git branch alone.
+	names := vcsVarNames
+	if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
+		names = []string{"_ENVY_VCS", "_ENVY_VCSROOT"}
+	}
+	return varActions(path, names, map[string]string{
+		"_ENVY_VCS":     "jj",
+		"_ENVY_VCSROOT": path,
+	})
+}
+
+// FossilRootCheck checks if a path is the root of a Fossil checkout. The
+// branch is stored in an SQLite database, so it is not set.
+type FossilRootCheck struct{}
+
+// Check implements the Checker interface.
+func (c FossilRootCheck) Check(path string) action.List {
+	if !paths.IsFile(filepath.Join(path, ".fslckout")) && !paths.IsFile(filepath.Join(path, "_FOSSIL_")) {
+		return nil
+	}
+	return varActions(path, vcsVarNames, map[string]string{
+		"_ENVY_VCS":     "fossil",
+		"_ENVY_VCSROOT": path,
+	})
+}
@@ -0,0 +1,93 @@
+package checkers
+
+import (
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/wojas/envy/action"
+	"github.com/wojas/envy/paths"
+)
+
+// VirtualEnvCheck checks for a Python virtualenv and activates it like its
+// activate script does. The Dirs are the names of the virtualenv dirs to
+// check, of which the first valid one is used.
+type VirtualEnvCheck struct {
+	Dirs []string
+}
+
+// Check implements the Checker interface.
+func (c VirtualEnvCheck) Check(path string) (actions action.List) {
+	for _, dir := range c.Dirs {
+		venv := filepath.Join(path, dir)
+		cfgPath := filepath.Join(venv, "pyvenv.cfg")
+		bin := filepath.Join(venv, "bin")
+		if !paths.IsFile(cfgPath) || !paths.IsDir(bin) {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(cfgPath)
+		if err != nil {
+			log.Printf("Warning: could not open %s: %v", shorten(cfgPath), err)
+			continue
+		}
+		cfg := parsePyvenvCfg(contents)
+		if cfg["home"] == "" {
+			log.Printf("Warning: %s is not a valid virtualenv config, 'home' is missing", shorten(cfgPath))
+			continue
+		}
+
+		// Python uses the name of the virtualenv dir if no prompt is set
+		prompt := cfg["prompt"]
+		if prompt == "" {
+			prompt = filepath.Base(venv)
+		}
+
+		return action.List{
+			{
+				Path:    path,
+				AddPath: bin,
+				Source:  cfgPath,
+			},
+			{
+				Path:        path,
+				SetEnv:      "VIRTUAL_ENV",
+				SetEnvValue: venv,
+				Source:      cfgPath,
+			},
+			{
+				Path:        path,
+				SetEnv:      "VIRTUAL_ENV_PROMPT",
+				SetEnvValue: prompt,
+				Source:      cfgPath,
+			},
+			{
+				// Would make the virtualenv use the wrong standard library
+				Path:     path,
+				UnsetEnv: "PYTHONHOME",
+				Source:   cfgPath,
+			},
+		}
+	}
+	return
+}
+
+// parsePyvenvCfg parses the "key = value" lines of a pyvenv.cfg file. Keys
+// are lowercase, and quotes around a value are removed.
+func parsePyvenvCfg(contents []byte) map[string]string {
+	cfg := make(map[string]string)
+	for _, line := range strings.Split(string(contents), "\n") {
+		idx := strings.IndexByte(line, '=')
+		if idx < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:idx]))
+		value := strings.TrimSpace(line[idx+1:])
+		if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		cfg[key] = value
+	}
+	return cfg
+}
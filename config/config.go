@@ -16,6 +16,8 @@ type Config struct {
 	AlwaysLoadHome bool `yaml:"always_load_home"`
 	// Allows changing tab and other colors with ENVY_COLOR
 	Colors map[string]string `yaml:"colors"`
+	// Names of Python virtualenv dirs to activate, in order of preference
+	VenvDirs []string `yaml:"venv_dirs"`
 }
 
 // Check validates a Config instance
@@ -59,5 +61,6 @@ func Default() *Config {
 	return &Config{
 		TrustedPaths:   trusted,
 		AlwaysLoadHome: true,
+		VenvDirs:       []string{".venv", "venv", "env"},
 	}
 }
@@ -0,0 +1,274 @@
+package dotenv
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var validKey = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Entry describes a single variable assignment in a file.
+type Entry struct {
+	Key   string
+	Value string
+	File  string
+	Line  int
+	// Literal is set for single quoted values, which must not be expanded
+	Literal bool
+}
+
+// SyntaxError describes an error in a single line of a file.
+type SyntaxError struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// Parse parses the contents of a .env style file. The file name is only used
+// for diagnostics. Unlike most .env parsers, it does not stop at the first
+// error: every valid line is returned in order of appearance, together with
+// all the syntax errors that were encountered.
+//
+// The supported syntax is:
+//
+//	# Comment
+//	KEY=unquoted value # inline comment
+//	export KEY='single quoted, no escapes'
+//	KEY="double quoted with \"escapes\",
+//	can span multiple lines"
+//
+// Variable references in values that are not single quoted are not expanded
+// by Parse, see Expand. For this, escaped '$' and '\' are left as is.
+func Parse(file string, contents []byte) (entries []Entry, errs []*SyntaxError) {
+	p := &parser{
+		file: file,
+		src:  string(contents),
+		line: 1,
+	}
+	p.parse()
+	return p.entries, p.errs
+}
+
+type parser struct {
+	file    string
+	src     string
+	pos     int
+	line    int
+	entries []Entry
+	errs    []*SyntaxError
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *parser) errorf(line int, format string, args ...interface{}) {
+	p.errs = append(p.errs, &SyntaxError{
+		File: p.file,
+		Line: line,
+		Msg:  fmt.Sprintf(format, args...),
+	})
+}
+
+// skipSpace skips whitespace, but not newlines.
+func (p *parser) skipSpace() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// skipLine skips to the end of the current line, without consuming the newline.
+func (p *parser) skipLine() {
+	if idx := strings.IndexByte(p.src[p.pos:], '\n'); idx >= 0 {
+		p.pos += idx
+	} else {
+		p.pos = len(p.src)
+	}
+}
+
+// word reads a run of characters up to whitespace, '=' or a comment.
+func (p *parser) word() string {
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(" \t\r\n=#", rune(p.peek())) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *parser) parse() {
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return
+		}
+		switch p.peek() {
+		case '\n':
+			p.pos++
+			p.line++
+		case '#':
+			p.skipLine()
+		default:
+			p.parseLine()
+		}
+	}
+}
+
+// parseLine parses a single assignment. On error, the rest of the line is
+// skipped, so that parsing can continue with the next line.
+func (p *parser) parseLine() {
+	line := p.line
+	key := p.word()
+	if key == "export" && strings.ContainsRune(" \t", rune(p.peek())) {
+		p.skipSpace()
+		key = p.word()
+	}
+	if !validKey.MatchString(key) {
+		if key == "" {
+			p.errorf(line, "unexpected character %q", p.peek())
+		} else {
+			p.errorf(line, "invalid key %q", key)
+		}
+		p.skipLine()
+		return
+	}
+
+	p.skipSpace()
+	if p.peek() != '=' {
+		p.errorf(line, "missing '=' after %s", key)
+		p.skipLine()
+		return
+	}
+	p.pos++
+	p.skipSpace()
+
+	var value string
+	var ok bool
+	literal := p.peek() == '\''
+	switch p.peek() {
+	case '\'':
+		value, ok = p.singleQuoted()
+	case '"':
+		value, ok = p.doubleQuoted()
+	default:
+		value, ok = p.unquoted(), true
+	}
+	if !ok {
+		p.errorf(line, "unterminated quote")
+		p.skipLine()
+		return
+	}
+
+	// Only whitespace and comments are allowed after a quoted value
+	p.skipSpace()
+	if p.peek() == '#' {
+		p.skipLine()
+	}
+	if !p.eof() && p.peek() != '\n' {
+		p.errorf(p.line, "unexpected character %q after quoted value", p.peek())
+		p.skipLine()
+		return
+	}
+
+	p.entries = append(p.entries, Entry{
+		Key:     key,
+		Value:   value,
+		File:    p.file,
+		Line:    line,
+		Literal: literal,
+	})
+}
+
+// singleQuoted reads a value in single quotes, which cannot contain escapes
+// or span multiple lines.
+func (p *parser) singleQuoted() (string, bool) {
+	start := p.pos + 1
+	for i := start; i < len(p.src); i++ {
+		switch p.src[i] {
+		case '\'':
+			p.pos = i + 1
+			return p.src[start:i], true
+		case '\n':
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// doubleQuoted reads a value in double quotes, which can contain backslash
+// escapes and newlines. On error, the position is left unchanged.
+func (p *parser) doubleQuoted() (string, bool) {
+	var b strings.Builder
+	lines := 0
+	for i := p.pos + 1; i < len(p.src); i++ {
+		c := p.src[i]
+		switch c {
+		case '"':
+			p.pos = i + 1
+			p.line += lines
+			return b.String(), true
+		case '\n':
+			lines++
+		case '\\':
+			if i+1 < len(p.src) {
+				i++
+				switch e := p.src[i]; e {
+				case 'n':
+					c = '\n'
+				case 't':
+					c = '\t'
+				case 'r':
+					c = '\r'
+				case '"':
+					c = e
+				case '\\', '$':
+					// Handled by Expand
+					b.WriteByte('\\')
+					c = e
+				case '\n':
+					// Line continuation
+					lines++
+					continue
+				default:
+					b.WriteByte('\\')
+					c = e
+				}
+			}
+		}
+		b.WriteByte(c)
+	}
+	return "", false
+}
+
+// unquoted reads an unquoted value up to the end of the line or an inline
+// comment, which must be preceded by whitespace.
+func (p *parser) unquoted() string {
+	start := p.pos
+	for !p.eof() {
+		c := p.peek()
+		if c == '\n' {
+			break
+		}
+		if c == '#' && p.pos > start && strings.ContainsRune(" \t", rune(p.src[p.pos-1])) {
+			break
+		}
+		p.pos++
+	}
+	return strings.TrimRight(p.src[start:p.pos], " \t\r")
+}
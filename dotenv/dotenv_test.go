@@ -0,0 +1,147 @@
+package dotenv
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		entries []Entry
+		errs    []SyntaxError
+	}{
+		{
+			name: "empty",
+			src:  "",
+		},
+		{
+			name: "comments and blank lines",
+			src:  "# comment\n\n  # indented\nA=1\n",
+			entries: []Entry{
+				{Key: "A", Value: "1", Line: 4},
+			},
+		},
+		{
+			name: "unquoted",
+			src:  "A=foo bar  \nB = baz # comment\nC=a#b\nD=\n",
+			entries: []Entry{
+				{Key: "A", Value: "foo bar", Line: 1},
+				{Key: "B", Value: "baz", Line: 2},
+				{Key: "C", Value: "a#b", Line: 3},
+				{Key: "D", Value: "", Line: 4},
+			},
+		},
+		{
+			name: "export",
+			src:  "export A=1\nexport\tB='2'\nexport=3\n",
+			entries: []Entry{
+				{Key: "A", Value: "1", Line: 1},
+				{Key: "B", Value: "2", Line: 2, Literal: true},
+				{Key: "export", Value: "3", Line: 3},
+			},
+		},
+		{
+			name: "single quoted",
+			src:  `A='no \n escapes $HOME' # comment` + "\n",
+			entries: []Entry{
+				{Key: "A", Value: `no \n escapes $HOME`, Line: 1, Literal: true},
+			},
+		},
+		{
+			name: "double quoted escapes",
+			src:  `A="a\nb\t\"c\" \$D \\ \x"` + "\n",
+			entries: []Entry{
+				{Key: "A", Value: "a\nb\t\"c\" \\$D \\\\ \\x", Line: 1},
+			},
+		},
+		{
+			name: "multi-line values",
+			src:  "A=\"one\ntwo\"\nB=\"three \\\nfour\"\nC=5\n",
+			entries: []Entry{
+				{Key: "A", Value: "one\ntwo", Line: 1},
+				{Key: "B", Value: "three four", Line: 3},
+				{Key: "C", Value: "5", Line: 5},
+			},
+		},
+		{
+			name: "unterminated single quote",
+			src:  "A='foo\nB=2\n",
+			entries: []Entry{
+				{Key: "B", Value: "2", Line: 2},
+			},
+			errs: []SyntaxError{
+				{Line: 1, Msg: "unterminated quote"},
+			},
+		},
+		{
+			name: "unterminated double quote",
+			src:  "A=1\nB=\"foo\nC=2\n",
+			entries: []Entry{
+				{Key: "A", Value: "1", Line: 1},
+				{Key: "C", Value: "2", Line: 3},
+			},
+			errs: []SyntaxError{
+				{Line: 2, Msg: "unterminated quote"},
+			},
+		},
+		{
+			name: "junk after quoted value",
+			src:  "A='foo' bar\nB=\"x\ny\"z\nC=3\n",
+			entries: []Entry{
+				{Key: "C", Value: "3", Line: 4},
+			},
+			errs: []SyntaxError{
+				{Line: 1, Msg: "unexpected character 'b' after quoted value"},
+				{Line: 3, Msg: "unexpected character 'z' after quoted value"},
+			},
+		},
+		{
+			name: "invalid lines",
+			src:  "1A=x\nB\n=y\nC-D=z\nE=ok\n",
+			entries: []Entry{
+				{Key: "E", Value: "ok", Line: 5},
+			},
+			errs: []SyntaxError{
+				{Line: 1, Msg: `invalid key "1A"`},
+				{Line: 2, Msg: "missing '=' after B"},
+				{Line: 3, Msg: "unexpected character '='"},
+				{Line: 4, Msg: `invalid key "C-D"`},
+			},
+		},
+		{
+			name: "no trailing newline",
+			src:  "A=1\r\nB='2'",
+			entries: []Entry{
+				{Key: "A", Value: "1", Line: 1},
+				{Key: "B", Value: "2", Line: 2, Literal: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, errs := Parse("test.env", []byte(tt.src))
+
+			var want []Entry
+			for _, e := range tt.entries {
+				e.File = "test.env"
+				want = append(want, e)
+			}
+			if !reflect.DeepEqual(entries, want) {
+				t.Errorf("entries:\n got %+v\nwant %+v", entries, want)
+			}
+
+			var gotErrs []SyntaxError
+			for _, e := range errs {
+				if e.File != "test.env" {
+					t.Errorf("error %v has file %q", e, e.File)
+				}
+				gotErrs = append(gotErrs, SyntaxError{Line: e.Line, Msg: e.Msg})
+			}
+			if !reflect.DeepEqual(gotErrs, tt.errs) {
+				t.Errorf("errors:\n got %+v\nwant %+v", gotErrs, tt.errs)
+			}
+		})
+	}
+}
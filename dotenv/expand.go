@@ -0,0 +1,112 @@
+package dotenv
+
+import (
+	"strings"
+)
+
+// Lookup returns the value of a variable and whether it is defined.
+type Lookup func(key string) (string, bool)
+
+// Expand expands shell style variable references in s:
+//
+//	$VAR ${VAR}         value of VAR
+//	${VAR:-default}     default if VAR is undefined or empty
+//	${VAR-default}      default if VAR is undefined
+//
+// The default can itself contain references. A literal '$' or '\' can be
+// escaped with a backslash. Expand returns the names of all undefined
+// variables that were referenced without a default.
+func Expand(s string, lookup Lookup) (string, []string) {
+	var b strings.Builder
+	var undefined []string
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\\' && i+1 < len(s) && (s[i+1] == '$' || s[i+1] == '\\') {
+			i++
+			b.WriteByte(s[i])
+			continue
+		}
+		if c != '$' || i+1 == len(s) {
+			b.WriteByte(c)
+			continue
+		}
+
+		// Plain $VAR
+		if s[i+1] != '{' {
+			n := nameLen(s[i+1:])
+			if n == 0 {
+				b.WriteByte(c) // Not a reference, literal '$'
+				continue
+			}
+			key := s[i+1 : i+1+n]
+			val, ok := lookup(key)
+			if !ok {
+				undefined = append(undefined, key)
+			}
+			b.WriteString(val)
+			i += n
+			continue
+		}
+
+		// ${VAR}, ${VAR-default} and ${VAR:-default}
+		end := closingBrace(s, i+2)
+		if end < 0 {
+			b.WriteString(s[i:]) // Unterminated, leave as is
+			break
+		}
+		expr := s[i+2 : end]
+		i = end
+		n := nameLen(expr)
+		key, rest := expr[:n], expr[n:]
+		if n == 0 || (rest != "" && !strings.HasPrefix(rest, "-") && !strings.HasPrefix(rest, ":-")) {
+			b.WriteString("${" + expr + "}") // Not a supported reference
+			continue
+		}
+		val, ok := lookup(key)
+		switch {
+		case strings.HasPrefix(rest, ":-") && val == "", strings.HasPrefix(rest, "-") && !ok:
+			def := rest[strings.IndexByte(rest, '-')+1:]
+			var u []string
+			val, u = Expand(def, lookup)
+			undefined = append(undefined, u...)
+		case !ok:
+			undefined = append(undefined, key)
+		}
+		b.WriteString(val)
+	}
+	return b.String(), undefined
+}
+
+// nameLen returns the length of the variable name at the start of s.
+func nameLen(s string) int {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			return i
+		}
+	}
+	return len(s)
+}
+
+// closingBrace returns the index of the '}' that closes a '${' before the
+// given start index, taking nested references into account.
+func closingBrace(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
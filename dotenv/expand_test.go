@@ -0,0 +1,58 @@
+package dotenv
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	vars := map[string]string{
+		"HOME":  "/home/user",
+		"EMPTY": "",
+		"A1":    "x",
+	}
+	lookup := func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	}
+
+	tests := []struct {
+		in        string
+		out       string
+		undefined []string
+	}{
+		{"", "", nil},
+		{"plain", "plain", nil},
+		{"$HOME/bin", "/home/user/bin", nil},
+		{"${HOME}bin", "/home/userbin", nil},
+		{"$A1$A1", "xx", nil},
+		{"$A1-1", "x-1", nil},
+		{"$MISSING/bin", "/bin", []string{"MISSING"}},
+		{"${MISSING}", "", []string{"MISSING"}},
+		{"${MISSING-def}", "def", nil},
+		{"${MISSING:-def}", "def", nil},
+		{"${EMPTY-def}", "", nil},
+		{"${EMPTY:-def}", "def", nil},
+		{"${HOME:-def}", "/home/user", nil},
+		{"${MISSING:-$HOME/x}", "/home/user/x", nil},
+		{"${MISSING:-${OTHER}}", "", []string{"OTHER"}},
+		{"${MISSING:-{a}}", "{a}", nil},
+		{`\$HOME`, "$HOME", nil},
+		{`\\$HOME`, `\/home/user`, nil},
+		{`a\b`, `a\b`, nil},
+		{"$", "$", nil},
+		{"$1 $-", "$1 $-", nil},
+		{"${HOME", "${HOME", nil},
+		{"${HOME:=x}", "${HOME:=x}", nil},
+		{"${}", "${}", nil},
+	}
+	for _, tt := range tests {
+		out, undefined := Expand(tt.in, lookup)
+		if out != tt.out {
+			t.Errorf("Expand(%q) = %q, want %q", tt.in, out, tt.out)
+		}
+		if !reflect.DeepEqual(undefined, tt.undefined) {
+			t.Errorf("Expand(%q) undefined = %v, want %v", tt.in, undefined, tt.undefined)
+		}
+	}
+}
@@ -1,48 +1,134 @@
 package env
 
 import (
+	"encoding/json"
 	"os"
 	"sort"
 )
 
+// Value is the value of an environment variable, which can also be unset.
+// It is marshaled to JSON as a string, or null if unset.
+type Value struct {
+	Val   string
+	Unset bool
+}
+
+// MarshalJSON implements json.Marshaler.
+func (v Value) MarshalJSON() ([]byte, error) {
+	if v.Unset {
+		return []byte("null"), nil
+	}
+	return json.Marshal(v.Val)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (v *Value) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*v = Value{Unset: true}
+		return nil
+	}
+	*v = Value{}
+	return json.Unmarshal(data, &v.Val)
+}
+
 // Env keeps track of changes to environment variables
 type Env struct {
-	changed  map[string]string
+	changed  map[string]Value
 	restored map[string]bool
+	base     map[string]string // Used instead of the process environment
 }
 
 // New returns a new Env
 func New() *Env {
 	return &Env{
-		changed:  make(map[string]string),
+		changed:  make(map[string]Value),
 		restored: make(map[string]bool),
 	}
 }
 
+// NewEmpty returns a new Env that starts without any variables, instead of
+// the environment of the process.
+func NewEmpty() *Env {
+	return NewFrom(make(map[string]string))
+}
+
+// NewFrom returns a new Env that starts with the given variables, instead of
+// the environment of the process.
+func NewFrom(base map[string]string) *Env {
+	e := New()
+	e.base = base
+	if e.base == nil {
+		e.base = make(map[string]string)
+	}
+	return e
+}
+
 // Get returns the current value for an environment variable.
 func (e *Env) Get(key string) string {
+	return e.Value(key).Val
+}
+
+// Lookup returns the current value for an environment variable and whether
+// it is set.
+func (e *Env) Lookup(key string) (string, bool) {
+	v := e.Value(key)
+	return v.Val, !v.Unset
+}
+
+// Value returns the current value for an environment variable, which records
+// if it is unset.
+func (e *Env) Value(key string) Value {
 	if val, exists := e.changed[key]; exists {
 		return val
 	}
-	return os.Getenv(key)
+	if e.base != nil {
+		val, set := e.base[key]
+		return Value{Val: val, Unset: !set}
+	}
+	val, set := os.LookupEnv(key)
+	return Value{Val: val, Unset: !set}
 }
 
 // Set sets an environment variable to a new value.
 func (e *Env) Set(key, val string) {
-	e.changed[key] = val
+	e.changed[key] = Value{Val: val}
 	delete(e.restored, key)
 }
 
-// Restore sets an environment variable to a previous value and marks it as restored.
-func (e *Env) Restore(key, val string) {
+// Unset removes an environment variable.
+func (e *Env) Unset(key string) {
+	e.changed[key] = Value{Unset: true}
+	delete(e.restored, key)
+}
+
+// Restore sets an environment variable to a previous value, which can be
+// unset, and marks it as restored.
+func (e *Env) Restore(key string, val Value) {
 	e.changed[key] = val
 	e.restored[key] = true
 }
 
+// Map returns all variables after the changes. This is only supported for an
+// Env that does not use the process environment.
+func (e *Env) Map() map[string]string {
+	m := make(map[string]string, len(e.base))
+	for k, v := range e.base {
+		m[k] = v
+	}
+	for k, v := range e.changed {
+		if v.Unset {
+			delete(m, k)
+		} else {
+			m[k] = v.Val
+		}
+	}
+	return m
+}
+
 // Changes returns all changes to environment variables
 func (e *Env) Changes() (changes ChangeList) {
 	for k, v := range e.changed {
-		changes = append(changes, Change{k, v, e.restored[k]})
+		changes = append(changes, Change{k, v.Val, v.Unset, e.restored[k]})
 	}
 	sort.Sort(changes)
 	return
@@ -52,6 +138,7 @@ func (e *Env) Changes() (changes ChangeList) {
 type Change struct {
 	Key      string
 	Val      string
+	Unset    bool
 	Restored bool
 }
 
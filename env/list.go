@@ -0,0 +1,177 @@
+package env
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// List contains operations for the paths in a PATH-like env var, which is a
+// list of paths separated by ':'.
+type List struct {
+	Name    string
+	Base    []string // Paths in the env var that were not added by envy
+	orig    []string
+	revPath []string
+}
+
+// NewList returns a new List for the env var with the given name.
+func NewList(name string, paths []string) *List {
+	return &List{
+		Name:    name,
+		Base:    paths,
+		orig:    paths,
+		revPath: ReversePaths(paths),
+	}
+}
+
+// NewPath returns a new List for PATH
+func NewPath(paths []string) *List {
+	return NewList("PATH", paths)
+}
+
+// Add adds a path to the front of the list of paths.
+func (p *List) Add(path string) {
+	p.revPath = append(p.revPath, path) // NOTE: reverse list, so append
+}
+
+// Append adds a path to the end of the list of paths.
+func (p *List) Append(path string) {
+	p.revPath = append([]string{path}, p.revPath...)
+}
+
+// Remove removes a path from the list of paths.
+func (p *List) Remove(path string) {
+	for i, x := range p.revPath {
+		if x == path {
+			p.revPath = append(p.revPath[:i], p.revPath[i+1:]...)
+			return
+		}
+	}
+	return
+}
+
+// Has checks if a path is already included in the list of paths.
+func (p *List) Has(path string) bool {
+	for _, x := range p.revPath {
+		if x == path {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the list of paths.
+func (p *List) Get() []string {
+	return ReversePaths(p.revPath)
+}
+
+// GetReversed returns the list of paths reversed.
+func (p *List) GetReversed() []string {
+	return p.revPath
+}
+
+// String returns the list of paths joined with the list separator, as used
+// for the value of the env var.
+func (p *List) String() string {
+	return strings.Join(p.Get(), string(filepath.ListSeparator))
+}
+
+// Changed checks if the list differs from the original value of the env var.
+func (p *List) Changed() bool {
+	return strings.Join(p.orig, "\x00") != strings.Join(p.Get(), "\x00")
+}
+
+// Added returns the paths that were not in the original value, in order.
+func (p *List) Added() (added []string) {
+	orig := toSet(p.orig)
+	for _, x := range p.Get() {
+		if !orig[x] {
+			added = append(added, x)
+		}
+	}
+	return added
+}
+
+// Removed returns the original paths that are no longer in the list, sorted.
+func (p *List) Removed() (removed []string) {
+	current := toSet(p.revPath)
+	for _, x := range p.orig {
+		if !current[x] {
+			removed = append(removed, x)
+		}
+	}
+	sort.Strings(removed)
+	return removed
+}
+
+func toSet(a []string) map[string]bool {
+	set := make(map[string]bool, len(a))
+	for _, x := range a {
+		set[x] = true
+	}
+	return set
+}
+
+// ReversePaths reverses a list of paths and returns a new slice.
+func ReversePaths(a []string) []string {
+	res := make([]string, len(a))
+	n := len(a)
+	for i, p := range a {
+		res[n-i-1] = p
+	}
+	return res
+}
+
+// Lists keeps track of changes to PATH and other list env vars. Each list
+// starts from its Base: the current value of the env var without the paths
+// that were previously added by envy. Adding paths to these in a fixed order
+// makes the result independent of the previous values.
+type Lists struct {
+	env   *Env
+	owned map[string]map[string]bool
+	lists map[string]*List
+}
+
+// NewLists returns a new Lists that loads the current values from env.
+// The owned paths by var name are the ones previously added by envy.
+func NewLists(env *Env, owned map[string]map[string]bool) *Lists {
+	return &Lists{
+		env:   env,
+		owned: owned,
+		lists: make(map[string]*List),
+	}
+}
+
+// Get returns the List for an env var, which is loaded on first use.
+func (l *Lists) Get(name string) *List {
+	list, ok := l.lists[name]
+	if !ok {
+		var paths []string
+		if val := l.env.Get(name); val != "" {
+			paths = filepath.SplitList(val)
+		}
+		list = NewList(name, paths)
+		for p := range l.owned[name] {
+			list.Remove(p)
+		}
+		list.Base = list.Get()
+		l.lists[name] = list
+	}
+	return list
+}
+
+// All returns all lists, including the ones that only had owned paths,
+// sorted by name.
+func (l *Lists) All() (all []*List) {
+	for name := range l.owned {
+		l.Get(name)
+	}
+	for _, list := range l.lists {
+		all = append(all, list)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Name < all[j].Name
+	})
+	return all
+}
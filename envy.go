@@ -2,9 +2,11 @@ package main
 
 import (
 	"flag"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime/trace"
 	"sort"
 	"strings"
@@ -20,7 +22,9 @@ import (
 )
 
 var traceFile = flag.String("trace", "", "Write trace to given file for use with `go tool trace`")
-var fish = flag.Bool("fish", false, "Output fish shell commands instead of the default bash/zsh commands.")
+var shellName = flag.String("shell", "bash", "Shell to output commands for: "+strings.Join(shell.Names, ", "))
+var fish = flag.Bool("fish", false, "Deprecated: use -shell=fish")
+var format = flag.String("format", "shell", "Output format: 'shell' for shell commands, or 'json' for a JSON document for editor integrations.")
 
 const ConfigFile = ".envy.yml"
 
@@ -31,31 +35,37 @@ const (
 )
 
 // getActions checks all paths for Actions using the checkers.
-func getActions(paths []string) (actions action.List) {
-	ac := make(chan action.Action)
+func getActions(paths []string, all []checkers.Checker) (actions action.List) {
+	// Results are collected per path and checker, to keep the order of the
+	// actions independent of the order in which the checkers finish.
+	results := make([][]action.List, len(paths))
 	var wg sync.WaitGroup
 
-	for _, p := range paths {
-		for _, c := range checkers.AllCheckers {
+	for i, p := range paths {
+		results[i] = make([]action.List, len(all))
+		for j, c := range all {
 			wg.Add(1)
-			go func(path string, c checkers.Checker) {
-				for _, a := range c.Check(path) {
-					ac <- a
+			go func(i, j int, path string, c checkers.Checker) {
+				actions := c.Check(path)
+				name := reflect.TypeOf(c).Name()
+				for k := range actions {
+					actions[k].Checker = name
 				}
+				results[i][j] = actions
 				wg.Done()
-			}(p, c)
+			}(i, j, p, c)
 		}
 	}
+	wg.Wait()
 
-	go func() {
-		wg.Wait()
-		close(ac)
-	}()
-
-	for a := range ac {
-		actions = append(actions, a)
+	for _, pathResults := range results {
+		for _, r := range pathResults {
+			actions = append(actions, r...)
+		}
 	}
-	sort.Sort(actions) // shallow paths first
+	// Shallow paths first. The sort must be stable to keep the order in which
+	// a checker returned its actions, like the lines of a .envy file.
+	sort.Stable(actions)
 	return actions
 }
 
@@ -79,16 +89,46 @@ func main() {
 		defer trace.Stop()
 	}
 
-	// Options set through environment variables
-	debug := os.Getenv("envy_debug") != ""
+	// As a shebang interpreter, all arguments are passed as a single one
+	args := flag.Args()
+	if len(args) > 0 && strings.HasPrefix(args[0], "exec ") {
+		args = append(strings.Fields(args[0]), args[1:]...)
+	}
 
-	// Get configuration
-	home, err := paths.HomeDir()
-	if err != nil {
-		log.Fatalf("Could not determine home dir: %v", nil)
+	var cmd string
+	if len(args) > 0 {
+		cmd = args[0]
 	}
+	switch cmd {
+	case "", "session":
+		runSession(false)
+	case "on":
+		runSession(true)
+	case "off":
+		runOff()
+	case "allow":
+		runAllow(flag.Arg(1))
+	case "deny":
+		runDeny(flag.Arg(1))
+	case "trust":
+		runTrust(args[1:])
+	case "status":
+		runStatus(args[1:])
+	case "exec":
+		runExec(args[1:])
+	case "export":
+		runExport(args[1:])
+	case "init":
+		runInit(args[1:])
+	default:
+		log.Fatalf("Unknown command: %s", cmd)
+	}
+}
+
+// loadConfig loads the user's config file.
+func loadConfig(home string, debug bool) *config.Config {
 	conf := config.Default()
-	err = conf.LoadYAMLFile(filepath.Join(home, ConfigFile))
+	err := conf.LoadYAMLFile(filepath.Join(home, ConfigFile))
 	if err != nil && !os.IsNotExist(err) {
 		log.Printf("Could not open ~/%s config file: %v", ConfigFile, err)
 	}
@@ -98,6 +138,25 @@ func main() {
 	if debug {
 		log.Printf("Effective config:\n%s", conf)
 	}
+	return conf
+}
+
+// runSession updates the environment for the current working directory and
+// prints the shell commands to apply the changes. If envy was suspended with
+// 'envy off', it does nothing, unless enable is set.
+func runSession(enable bool) {
+	// Created first, because the shell can capture log messages
+	sh := getShell()
+
+	// Options set through environment variables
+	debug := os.Getenv("envy_debug") != ""
+
+	// Get configuration
+	home, err := paths.HomeDir()
+	if err != nil {
+		log.Fatalf("Could not determine home dir: %v", err)
+	}
+	conf := loadConfig(home, debug)
 
 	// Get information about our environment
 	cwd, err := os.Getwd()
@@ -113,21 +172,90 @@ func main() {
 
 	// Load session info from environment
 	ses := session.Load(os.Getenv("_envy_session"))
-	ses.Path = cwd
+	if ses.Disabled {
+		if !enable {
+			render(sh, shell.NewScript()) // Suspended with 'envy off'
+			return
+		}
+		ses.Disabled = false
+		log.Printf("enabled")
+	}
 
-	// Load environment to perform magic on
-	path := environ.NewPath(filepath.SplitList(os.Getenv("PATH")))
 	env := environ.New()
+	oldAliases := shellAliases(ses)
+	lists, _, hooks := updateEnv(ses, env, cwd, conf, home, debug)
+	if len(ses.Aliases) > 0 {
+		ses.Shell = os.Getppid()
+	} else {
+		ses.Shell = 0
+	}
+
+	script := shell.NewScript()
+	addChanges(script, env, lists)
+	logChanges(env, lists, conf, shorten, debug)
+	addAliasChanges(script, oldAliases, ses.Aliases)
+	addHookCommands(script, hooks)
+
+	// Set new session.
+	// This one is exported too, so that if the user start a subshell,
+	// envy is aware of the changes in the parent shell.
+	script.SetEnv("_envy_session", session.Dump(ses))
+	render(sh, script)
+}
+
+// hookCommands contains the ENVY_ON_LEAVE and ENVY_ON_ENTER commands to run
+// for the directories that became inactive and active, in order.
+type hookCommands struct {
+	leave []string
+	enter []string
+}
+
+// updateEnv updates the session and env for a new working directory. It
+// returns the changes to list vars, the actions for the directory and the
+// commands to run for the directories that were left and entered.
+func updateEnv(ses *session.Session, env *environ.Env, cwd string, conf *config.Config, home string, debug bool) (*environ.Lists, action.List, hookCommands) {
+	ses.Path = cwd
+	shortenSource := paths.Shorten{Home: home}.Do
+
+	// Load environment to perform magic on.
+	// PATH and other list vars are rebuilt from scratch: their base value
+	// without any paths we added before, plus the paths added by the actions
+	// for the current working directory, in a fixed order. This way the order
+	// only depends on the current working directory, and not on the order in
+	// which the user visited directories.
+	lists := environ.NewLists(env, ses.ListPaths())
+	for _, u := range ses.Undo {
+		u.ClearLists()
+	}
+
+	// Aliases are tracked like env vars, but the shell does not tell us its
+	// current aliases, so these start from the ones we defined.
+	aliases := environ.NewFrom(ses.Aliases)
 
 	// Step 1: Undo previous changes if the user moved to a different working directory.
 	undo := ses.ToUndoFor(cwd)
 	for _, u := range undo {
-		for p := range u.Path {
-			path.Remove(p)
-		}
 		for k, v := range u.Env {
 			env.Restore(k, v)
 		}
+		for name, v := range u.Aliases {
+			aliases.Restore(name, v)
+		}
+	}
+
+	// Directories left are undone from deep to shallow, so that is also the
+	// order for their commands. Once recorded, the leave commands of active
+	// directories are kept, so that they still run if the .envy file was
+	// changed or is no longer allowed.
+	var hooks hookCommands
+	for _, u := range undo {
+		hooks.leave = append(hooks.leave, u.OnLeave...)
+	}
+	entered := make(map[string]bool)
+	leaveRecorded := make(map[string]bool)
+	for p, u := range ses.Undo {
+		entered[p] = u.Entered
+		leaveRecorded[p] = len(u.OnLeave) > 0
 	}
 
 	// Step 2: Perform actions for the current working directory.
@@ -141,28 +269,52 @@ func main() {
 	if debug {
 		log.Printf("Paths to check: %v", toCheck)
 	}
-	actions := getActions(toCheck)
+	// Variable references in .envy files are expanded against the environment
+	// as it was before envy changed it for the active paths.
+	baseline := func(key string) (string, bool) {
+		for _, u := range ses.PathUndoList() {
+			if v, exists := u.Env[key]; exists {
+				return v.Val, !v.Unset
+			}
+		}
+		return env.Lookup(key)
+	}
+	actions := checkers.Expand(getActions(toCheck, checkers.All(conf)), baseline)
 	seenEnvs := make(map[string]bool)
-	seenPaths := make(map[string]bool)
+	seenAliases := make(map[string]bool)
+	warnings := make(map[string][]string)
 	for _, a := range actions {
 		if debug {
 			log.Printf("action %#v", a)
 		}
 
+		// Warnings are shown once, until the directory is left, or the
+		// warning changes.
+		if a.Warning != "" {
+			if !ses.UndoFor(a.Path).HasWarning(a.Warning) {
+				log.Printf("Warning: %s", a.Warning)
+			}
+			warnings[a.Path] = append(warnings[a.Path], a.Warning)
+		}
+
 		if a.AddPath != "" {
-			p := a.AddPath
-			seenPaths[p] = true
-			if !path.Has(p) {
-				path.Add(p)
+			name, p := a.PathList(), a.AddPath
+			if list := lists.Get(name); !list.Has(p) {
+				if a.Append {
+					list.Append(p)
+				} else {
+					list.Add(p)
+				}
 				u := ses.UndoFor(a.Path)
-				u.Path[p] = true
+				u.AddListPath(name, p)
+				u.SetPathOrigin(p, a.Origin(shortenSource))
 			}
 		}
 
 		if a.SetEnv != "" {
 			k, v := a.SetEnv, a.SetEnvValue
 			seenEnvs[k] = true
-			if prevValue := env.Get(k); prevValue != v {
+			if prevValue := env.Value(k); prevValue.Unset || prevValue.Val != v {
 				env.Set(k, v)
 
 				// Only store current env value if we did not already store a
@@ -173,6 +325,50 @@ func main() {
 					u.Env[k] = prevValue
 				}
 			}
+			if u, exists := ses.Undo[a.Path]; exists {
+				if _, changed := u.Env[k]; changed {
+					u.SetOrigin(k, a.Origin(shortenSource))
+				}
+			}
+		}
+
+		if a.SetAlias != "" {
+			name, v := a.SetAlias, a.SetAliasValue
+			seenAliases[name] = true
+			if prevValue := aliases.Value(name); prevValue.Unset || prevValue.Val != v {
+				aliases.Set(name, v)
+				ses.UndoFor(a.Path).SetAliasUndo(name, prevValue)
+			}
+		}
+
+		// The enter commands run once, the first time we see them after the
+		// directory was entered, which can also be after it was allowed.
+		// Actions are sorted from shallow to deep, so the commands for
+		// deeper directories run last.
+		if a.OnEnter != "" && !entered[a.Path] {
+			hooks.enter = append(hooks.enter, a.OnEnter)
+			ses.UndoFor(a.Path).Entered = true
+		}
+		if a.OnLeave != "" && !leaveRecorded[a.Path] {
+			u := ses.UndoFor(a.Path)
+			u.OnLeave = append(u.OnLeave, a.OnLeave)
+		}
+
+		if a.UnsetEnv != "" {
+			k := a.UnsetEnv
+			seenEnvs[k] = true
+			if prevValue := env.Value(k); !prevValue.Unset {
+				env.Unset(k)
+				u := ses.UndoFor(a.Path)
+				if _, exists := u.Env[k]; !exists {
+					u.Env[k] = prevValue
+				}
+			}
+			if u, exists := ses.Undo[a.Path]; exists {
+				if _, changed := u.Env[k]; changed {
+					u.SetOrigin(k, a.Origin(shortenSource))
+				}
+			}
 		}
 	}
 
@@ -187,11 +383,11 @@ func main() {
 	//       vars that are changed by checkers anyway? But then we need to be
 	//       careful about which undo value we store in the session.
 
-	// These two are defined outside of the loop to also apply all removes from
+	// This is defined outside of the loop to also apply all removes from
 	// shallow paths to deeper ones.
 	// This relies on the undo items being sorted from shallow to deep paths.
 	removeEnvs := make([]string, 0)
-	removePaths := make([]string, 0)
+	removeAliases := make([]string, 0)
 	for _, u := range ses.PathUndoList() {
 		// For environment variables
 		for k, v := range u.Env {
@@ -203,90 +399,201 @@ func main() {
 		}
 		for _, k := range removeEnvs {
 			delete(u.Env, k) // Remove from session, no longer relevant
+			delete(u.Origin, k)
 		}
 
-		// For PATH elements
-		// TODO: If a shallower path reappears later, it will be added to the front.
-		//       This is undesirable. Avoiding this would require storing the
-		//       original PATH before we do any changes in a session.
-		for p := range u.Path {
-			if !seenPaths[p] {
-				path.Remove(p)
-				removePaths = append(removePaths, p)
-				seenPaths[p] = true // Prevent triggering again
+		// For aliases
+		for name, v := range u.Aliases {
+			if !seenAliases[name] {
+				aliases.Restore(name, v)
+				removeAliases = append(removeAliases, name)
+				seenAliases[name] = true
 			}
 		}
-		for _, p := range removePaths {
-			delete(u.Path, p)
+		for _, name := range removeAliases {
+			delete(u.Aliases, name)
 		}
 	}
+	ses.Aliases = aliases.Map()
+	for p, u := range ses.Undo {
+		u.Warnings = warnings[p]
+	}
+
+	return lists, actions, hooks
+}
 
-	// Print commands to perform environment changes for different shells
-	var sh shell.Shell
+// getShell returns the Shell selected with the command line flags.
+func getShell() shell.Shell {
+	if *format == "json" {
+		sh := shell.JSON()
+		// Include log messages in the output, without terminal colors
+		if w, ok := sh.(io.Writer); ok {
+			log.SetOutput(w)
+			log.SetPrefix("")
+		}
+		return sh
+	}
+	name := *shellName
 	if *fish {
-		sh = shell.Fish()
-	} else {
-		sh = shell.Bash() // Also used for zsh
+		name = "fish" // Used by hooks from before the -shell flag
+	}
+	sh, err := shell.New(name)
+	if err != nil {
+		log.Fatalf("%v", err)
 	}
+	return sh
+}
 
+// addChanges adds the changes to the env and lists to the script, skipping
+// those that the shell already has.
+func addChanges(script *shell.Script, env *environ.Env, lists *environ.Lists) {
+	for _, item := range env.Changes() {
+		if !isChange(item) {
+			continue
+		}
+		if item.Unset {
+			script.UnsetEnv(item.Key)
+		} else {
+			script.SetEnv(item.Key, item.Val)
+		}
+	}
+	for _, list := range lists.All() {
+		if !list.Changed() {
+			continue
+		}
+		if list.Name != "PATH" && len(list.Get()) == 0 {
+			script.UnsetEnv(list.Name) // Instead of leaving an empty var behind
+		} else {
+			script.SetList(list)
+		}
+	}
+}
+
+// isChange checks if an env change differs from the current environment.
+func isChange(item environ.Change) bool {
+	val, set := os.LookupEnv(item.Key)
+	if item.Unset {
+		return set
+	}
+	return !set || val != item.Val
+}
+
+// logChanges logs the changes to the env and lists for the user.
+func logChanges(env *environ.Env, lists *environ.Lists, conf *config.Config, shorten paths.Shorten, debug bool) {
 	// Environment changes
 	for _, item := range env.Changes() {
-		if os.Getenv(item.Key) != item.Val {
-			sh.SetEnv(item.Key, item.Val)
-			if strings.HasPrefix(item.Key, "_ENVY_") {
-				continue // Do not log gitroot env changes
-			}
+		if !isChange(item) {
+			continue
+		}
+		if strings.HasPrefix(item.Key, "_ENVY_") {
+			continue // Do not log gitroot env changes
+		}
 
-			if item.Restored {
-				log.Printf("restore: %s = %s", item.Key, shorten.Do(item.Val))
-			} else {
-				log.Printf("%s = %s", item.Key, shorten.Do(item.Val))
-			}
+		switch {
+		case item.Restored && item.Unset:
+			log.Printf("restore: unset %s", item.Key)
+		case item.Restored:
+			log.Printf("restore: %s = %s", item.Key, shorten.Do(item.Val))
+		case item.Unset:
+			log.Printf("unset %s", item.Key)
+		default:
+			log.Printf("%s = %s", item.Key, shorten.Do(item.Val))
+		}
 
-			// Easiest to implement when this changes
-			if item.Key == "ENVY_COLOR" {
-				color := item.Val
-				if color == "" {
-					color = "RESET"
-				}
-				s, exists := conf.Colors[color]
-				if !exists {
-					log.Printf("WARNING: Color %q not defined in ~/.envy.yml", color)
-				}
-				if debug {
-					log.Printf("Color: %s = %q", color, s)
-				}
-				_, _ = os.Stderr.WriteString(s) // no newline
+		// Easiest to implement when this changes
+		if item.Key == "ENVY_COLOR" {
+			color := item.Val
+			if color == "" {
+				color = "RESET"
+			}
+			s, exists := conf.Colors[color]
+			if !exists {
+				log.Printf("WARNING: Color %q not defined in ~/.envy.yml", color)
+			}
+			if debug {
+				log.Printf("Color: %s = %q", color, s)
 			}
+			_, _ = os.Stderr.WriteString(s) // no newline
 		}
 	}
 
-	// PATH changes
-	if path.Changed {
-		sh.SetPath(path)
+	// PATH and other list changes
+	for _, list := range lists.All() {
+		if !list.Changed() {
+			continue
+		}
 
 		// Print removed paths
-		var removed []string
-		for p := range path.Removed {
-			removed = append(removed, p)
-		}
-		sort.Strings(removed)
-		for _, p := range removed {
-			if !path.Added[p] {
-				log.Printf("restore: PATH -= %s", shorten.Do(p))
-			}
+		for _, p := range list.Removed() {
+			log.Printf("restore: %s -= %s", list.Name, shorten.Do(p))
 		}
 
 		// Print added paths
-		for _, p := range path.GetReversed() {
-			if path.Added[p] && !path.Removed[p] {
-				log.Printf("PATH += %s", shorten.Do(p))
-			}
+		added := list.Added()
+		for i := len(added) - 1; i >= 0; i-- {
+			log.Printf("%s += %s", list.Name, shorten.Do(added[i]))
 		}
 	}
+}
 
-	// Set new session.
-	// This one is exported too, so that if the user start a subshell,
-	// envy is aware of the changes in the parent shell.
-	sh.SetEnv("_envy_session", session.Dump(ses))
+// shellAliases returns the aliases envy defined in the shell that runs us.
+// The session is also inherited by new shells started from that shell, but
+// the aliases are not, so in a new shell none are defined yet.
+func shellAliases(ses *session.Session) map[string]string {
+	if ses.Shell != os.Getppid() {
+		return nil
+	}
+	return ses.Aliases
+}
+
+// addAliasChanges adds the differences between the old and new aliases to
+// the script, and logs them.
+func addAliasChanges(script *shell.Script, oldAliases, newAliases map[string]string) {
+	names := make([]string, 0, len(oldAliases)+len(newAliases))
+	for name := range oldAliases {
+		names = append(names, name)
+	}
+	for name := range newAliases {
+		if _, exists := oldAliases[name]; !exists {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		oldValue, wasSet := oldAliases[name]
+		newValue, set := newAliases[name]
+		switch {
+		case !set:
+			script.UnsetAlias(name)
+			log.Printf("unalias %s", name)
+		case !wasSet:
+			// The user may have defined this alias before we replace it
+			script.SaveAlias(name)
+			fallthrough
+		case oldValue != newValue:
+			script.SetAlias(name, newValue)
+			log.Printf("alias %s = %s", name, newValue)
+		}
+	}
+}
+
+// addHookCommands adds the ENVY_ON_LEAVE and ENVY_ON_ENTER commands to the
+// script, and logs them.
+func addHookCommands(script *shell.Script, hooks hookCommands) {
+	for _, cmd := range hooks.leave {
+		script.OnLeave(cmd)
+		log.Printf("on leave: %s", cmd)
+	}
+	for _, cmd := range hooks.enter {
+		script.OnEnter(cmd)
+		log.Printf("on enter: %s", cmd)
+	}
+}
+
+// render writes the script for the shell to stdout.
+func render(sh shell.Shell, script *shell.Script) {
+	if err := sh.Render(os.Stdout, script); err != nil {
+		log.Fatalf("Cannot write output: %v", err)
+	}
 }
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+
+	environ "github.com/wojas/envy/env"
+	"github.com/wojas/envy/paths"
+	"github.com/wojas/envy/session"
+	"github.com/wojas/envy/shell"
+)
+
+// runExec runs a command with the environment envy would set up for a
+// directory, without the need for a shell hook.
+//
+// It can also be used as a shebang interpreter, in which case the kernel
+// passes all interpreter arguments as a single one, like "exec bash":
+//
+//	#!/usr/local/bin/envy exec bash
+func runExec(args []string) {
+	fs := flag.NewFlagSet("exec", flag.ExitOnError)
+	dir := fs.String("dir", ".", "Directory to load the environment for")
+	_ = fs.Parse(args)
+	args = fs.Args()
+	if len(args) == 0 {
+		log.Fatalf("Usage: envy exec [--dir DIR] [--] command [args...]")
+	}
+
+	debug := os.Getenv("envy_debug") != ""
+	home, err := paths.HomeDir()
+	if err != nil {
+		log.Fatalf("Could not determine home dir: %v", err)
+	}
+	conf := loadConfig(home, debug)
+	absDir, err := filepath.Abs(*dir)
+	if err != nil {
+		log.Fatalf("Invalid dir %s: %v", *dir, err)
+	}
+
+	// Start from the current session, in case we are called from a shell that
+	// already has changes for another directory.
+	ses := session.Load(os.Getenv("_envy_session"))
+	ses.Disabled = false
+	env := environ.New()
+	lists, _, _ := updateEnv(ses, env, absDir, conf, home, debug)
+	script := shell.NewScript()
+	addChanges(script, env, lists)
+	script.SetEnv("_envy_session", session.Dump(ses))
+	applyToProcess(script)
+
+	// Looked up with the new PATH, to find tools in node_modules/.bin and such
+	bin, err := exec.LookPath(args[0])
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	err = syscall.Exec(bin, args, os.Environ())
+	log.Fatalf("Cannot exec %s: %v", bin, err)
+}
+
+// applyToProcess applies the changes in the script to the environment of this
+// process, which is inherited by commands we start.
+func applyToProcess(script *shell.Script) {
+	for _, op := range script.Ops() {
+		if v := op.Value(); v.Unset {
+			os.Unsetenv(op.Key)
+		} else {
+			os.Setenv(op.Key, v.Val)
+		}
+	}
+}
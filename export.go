@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	environ "github.com/wojas/envy/env"
+	"github.com/wojas/envy/export"
+	"github.com/wojas/envy/paths"
+	"github.com/wojas/envy/session"
+	"github.com/wojas/envy/shell"
+)
+
+// runExport prints the variables and PATH envy would set for a directory in
+// a format for use by other tools.
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "dotenv", "Output format: "+strings.Join(export.Formats, ", "))
+	empty := fs.Bool("empty", false, "Start from an empty environment instead of the current one")
+	_ = fs.Parse(args)
+	dir := "."
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+
+	debug := os.Getenv("envy_debug") != ""
+	home, err := paths.HomeDir()
+	if err != nil {
+		log.Fatalf("Could not determine home dir: %v", err)
+	}
+	conf := loadConfig(home, debug)
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		log.Fatalf("Invalid dir %s: %v", dir, err)
+	}
+
+	// With the current session, the values are computed against the
+	// environment as it was before envy changed it.
+	ses := session.New()
+	env := environ.NewEmpty()
+	if !*empty {
+		ses = session.Load(os.Getenv("_envy_session"))
+		env = environ.New()
+	}
+	lists, actions, _ := updateEnv(ses, env, absDir, conf, home, debug)
+
+	// Only export the vars the actions are about, not the whole environment
+	seen := make(map[string]bool)
+	script := shell.NewScript()
+	for _, a := range actions {
+		var key string
+		switch {
+		case a.SetEnv != "" && a.SetEnv != "ENVY_COLOR":
+			key = a.SetEnv
+		case a.UnsetEnv != "" && !strings.HasPrefix(a.UnsetEnv, "_ENVY_"):
+			// Unset git vars only serve to override a parent repository
+			key = a.UnsetEnv
+		case a.AddPath != "":
+			key = a.PathList()
+		default:
+			continue
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if a.AddPath != "" {
+			script.SetList(lists.Get(key))
+		} else if val, set := env.Lookup(key); set {
+			script.SetEnv(key, val)
+		} else {
+			script.UnsetEnv(key)
+		}
+	}
+
+	if err := export.Write(os.Stdout, *format, script); err != nil {
+		log.Fatalf("Cannot export: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/wojas/envy/env"
+	"github.com/wojas/envy/shell"
+)
+
+// Formats lists all supported output formats.
+var Formats = []string{"dotenv", "json", "systemd", "docker", "shell"}
+
+// Write writes the vars changed by the script to w in the given format. Vars
+// that cannot be represented in the format, like unset vars in a dotenv file,
+// are skipped with a warning.
+func Write(w io.Writer, format string, script *shell.Script) error {
+	vars := script.Ops()
+	switch format {
+	case "json":
+		return writeJSON(w, vars)
+	case "dotenv":
+		return writeLines(w, vars, format, dotenvLine)
+	case "systemd":
+		return writeLines(w, vars, format, systemdLine)
+	case "docker":
+		return writeLines(w, vars, format, dockerLine)
+	case "shell":
+		return writeLines(w, vars, format, shellLine)
+	default:
+		return fmt.Errorf("unknown format %q, use one of: %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+func writeJSON(w io.Writer, vars []shell.Op) error {
+	obj := make(map[string]env.Value, len(vars))
+	for _, v := range vars {
+		obj[v.Key] = v.Value() // Unset vars are null
+	}
+	blob, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", blob)
+	return err
+}
+
+// lineFunc returns the line for a var, or false if it cannot be represented.
+type lineFunc func(v shell.Op) (string, bool)
+
+func writeLines(w io.Writer, vars []shell.Op, format string, line lineFunc) error {
+	for _, v := range vars {
+		l, ok := line(v)
+		if !ok {
+			log.Printf("Warning: cannot export %s in %s format", v.Key, format)
+			continue
+		}
+		if _, err := fmt.Fprintln(w, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dotenvLine uses double quotes, with the escapes understood by envy and
+// most other .env parsers.
+func dotenvLine(v shell.Op) (string, bool) {
+	val := v.Value()
+	if val.Unset {
+		return "", false
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`)
+	return v.Key + `="` + r.Replace(val.Val) + `"`, true
+}
+
+// systemdLine uses double quotes for an EnvironmentFile. Within these, systemd
+// follows shell rules, so a newline can appear as is.
+func systemdLine(v shell.Op) (string, bool) {
+	val := v.Value()
+	if val.Unset {
+		return "", false
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`", `$`, `\$`)
+	return v.Key + `="` + r.Replace(val.Val) + `"`, true
+}
+
+// dockerLine writes the value as is, because docker does not support any
+// quoting in --env-file files. This means newlines are not possible.
+func dockerLine(v shell.Op) (string, bool) {
+	val := v.Value()
+	if val.Unset || strings.ContainsAny(val.Val, "\n\r") {
+		return "", false
+	}
+	return v.Key + "=" + val.Val, true
+}
+
+// shellLine writes POSIX shell commands. Within single quotes nothing can be
+// escaped, so for a single quote the quoting is ended, the quote is escaped
+// with a backslash, and the quoting is started again.
+func shellLine(v shell.Op) (string, bool) {
+	val := v.Value()
+	if val.Unset {
+		return "unset " + v.Key, true
+	}
+	return "export " + v.Key + "='" + strings.Replace(val.Val, "'", `'\''`, -1) + "'", true
+}
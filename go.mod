@@ -2,9 +2,6 @@ module github.com/wojas/envy
 
 go 1.19
 
-require (
-	github.com/subosito/gotenv v1.1.0
-	gopkg.in/yaml.v2 v2.4.0
-)
+require gopkg.in/yaml.v2 v2.4.0
 
 require github.com/stretchr/testify v1.8.4 // indirect
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/wojas/envy/shell"
+)
+
+// runInit prints the hook code for a shell.
+func runInit(args []string) {
+	if len(args) != 1 {
+		log.Fatalf("Usage: envy init %s", strings.Join(shell.Names, "|"))
+	}
+	hook, err := shell.Hook(args[0], binPath())
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	fmt.Print(hook)
+}
+
+// binPath returns the absolute path of the running envy binary. Symlinks are
+// not resolved, because package managers often link to a versioned path that
+// changes on every upgrade.
+func binPath() string {
+	bin := os.Args[0]
+	if !strings.ContainsRune(bin, filepath.Separator) {
+		p, err := exec.LookPath(bin)
+		if err != nil {
+			p, err = os.Executable()
+		}
+		if err != nil {
+			return bin
+		}
+		bin = p
+	}
+	if abs, err := filepath.Abs(bin); err == nil {
+		return abs
+	}
+	return bin
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	environ "github.com/wojas/envy/env"
+	"github.com/wojas/envy/paths"
+	"github.com/wojas/envy/session"
+	"github.com/wojas/envy/shell"
+)
+
+// runOff undoes all changes in the current session and suspends envy until
+// 'envy on' is run.
+func runOff() {
+	sh := getShell()
+	debug := os.Getenv("envy_debug") != ""
+	home, err := paths.HomeDir()
+	if err != nil {
+		log.Fatalf("Could not determine home dir: %v", err)
+	}
+	conf := loadConfig(home, debug)
+	cwd, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	shorten := paths.Shorten{
+		Home:    home,
+		Current: cwd,
+	}
+
+	ses := session.Load(os.Getenv("_envy_session"))
+	if ses.Disabled {
+		log.Printf("already disabled")
+		render(sh, shell.NewScript())
+		return
+	}
+
+	// Loading every list var restores its base, without our paths
+	env := environ.New()
+	lists := environ.NewLists(env, ses.ListPaths())
+	lists.All()
+
+	var hooks hookCommands
+	for _, u := range ses.UndoAll() {
+		for k, v := range u.Env {
+			env.Restore(k, v)
+		}
+		hooks.leave = append(hooks.leave, u.OnLeave...)
+	}
+	ses.Disabled = true
+
+	script := shell.NewScript()
+	addChanges(script, env, lists)
+	logChanges(env, lists, conf, shorten, debug)
+	addAliasChanges(script, shellAliases(ses), nil)
+	addHookCommands(script, hooks)
+	ses.Aliases = nil
+	ses.Shell = 0
+	log.Printf("disabled, run 'envy on' to enable again")
+	script.SetEnv("_envy_session", session.Dump(ses))
+	render(sh, script)
+}
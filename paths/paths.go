@@ -59,6 +59,20 @@ func HomeDir() (string, error) {
 	return filepath.Clean(u.HomeDir), nil
 }
 
+// Resolve returns p as an absolute and cleaned path. A leading '~' is
+// replaced with the home dir, and a relative path is taken relative to dir.
+func Resolve(p, dir string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := HomeDir(); err == nil {
+			p = home + p[1:]
+		}
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(dir, p)
+	}
+	return filepath.Clean(p)
+}
+
 // Shorten shortens paths by replacing the home dir with '~' and current
 // dir with '.' for display.
 type Shorten struct {
@@ -68,10 +82,10 @@ type Shorten struct {
 
 // Do performs the actual shortening of a path.
 func (s Shorten) Do(p string) string {
-	if IsSubpath(p, s.Current) {
+	if s.Current != "" && IsSubpath(p, s.Current) {
 		return "." + p[len(s.Current):]
 	}
-	if IsSubpath(p, s.Home) {
+	if s.Home != "" && IsSubpath(p, s.Home) {
 		return "~" + p[len(s.Home):]
 	}
 	return p
@@ -5,23 +5,136 @@ import (
 	"log"
 	"sort"
 
+	"github.com/wojas/envy/env"
 	"github.com/wojas/envy/paths"
 )
 
 // PathUndo describes the actions to undo for a single path
 type PathUndo struct {
-	Env  map[string]string // Environment vars to restore
-	Path map[string]bool   // Paths to remove from PATH
+	Env   map[string]env.Value // Environment vars to restore
+	Path  []string             // Paths added to PATH, in order of addition
+	Lists map[string][]string  `json:",omitempty"` // Paths added to other list vars
+	// Shell aliases to restore, which are unset if there was none
+	Aliases map[string]env.Value `json:",omitempty"`
+	// Commands to run when the path is no longer active
+	OnLeave []string `json:",omitempty"`
+	// Entered is set once the enter commands for the path were run
+	Entered bool `json:",omitempty"`
+	// Warnings that were shown for the path, which are not shown again
+	Warnings []string `json:",omitempty"`
+	// Where the changes came from, for display, by var name and added path
+	Origin     map[string]string `json:",omitempty"`
+	PathOrigin map[string]string `json:",omitempty"`
 }
 
 // NewPathUndo created a new PathUndo
 func NewPathUndo() *PathUndo {
 	return &PathUndo{
-		Env:  make(map[string]string),
-		Path: make(map[string]bool),
+		Env:        make(map[string]env.Value),
+		Lists:      make(map[string][]string),
+		Origin:     make(map[string]string),
+		PathOrigin: make(map[string]string),
 	}
 }
 
+// UnmarshalJSON implements json.Unmarshaler. Sessions of older releases
+// stored the added paths as a map, which is still accepted so that these
+// paths are removed after an upgrade.
+func (u *PathUndo) UnmarshalJSON(data []byte) error {
+	type pathUndo PathUndo // Without this method
+	aux := struct {
+		*pathUndo
+		Path json.RawMessage
+	}{pathUndo: (*pathUndo)(u)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	u.Path = nil
+	if len(aux.Path) == 0 || string(aux.Path) == "null" {
+		return nil
+	}
+	if aux.Path[0] != '{' {
+		return json.Unmarshal(aux.Path, &u.Path)
+	}
+	var old map[string]bool
+	if err := json.Unmarshal(aux.Path, &old); err != nil {
+		return err
+	}
+	for p := range old {
+		u.Path = append(u.Path, p)
+	}
+	sort.Strings(u.Path)
+	return nil
+}
+
+// SetOrigin records where the change to a var came from.
+func (u *PathUndo) SetOrigin(key, origin string) {
+	if u.Origin == nil {
+		u.Origin = make(map[string]string)
+	}
+	u.Origin[key] = origin
+}
+
+// SetPathOrigin records where a path added to a list var came from.
+func (u *PathUndo) SetPathOrigin(p, origin string) {
+	if u.PathOrigin == nil {
+		u.PathOrigin = make(map[string]string)
+	}
+	u.PathOrigin[p] = origin
+}
+
+// SetAliasUndo records the previous definition of an alias, unless one was
+// already recorded.
+func (u *PathUndo) SetAliasUndo(name string, prev env.Value) {
+	if u.Aliases == nil {
+		u.Aliases = make(map[string]env.Value)
+	}
+	if _, exists := u.Aliases[name]; !exists {
+		u.Aliases[name] = prev
+	}
+}
+
+// HasWarning returns true if the warning was already shown for the path.
+func (u *PathUndo) HasWarning(warning string) bool {
+	for _, w := range u.Warnings {
+		if w == warning {
+			return true
+		}
+	}
+	return false
+}
+
+// AddListPath records a path added to a list env var, like PATH.
+func (u *PathUndo) AddListPath(name, p string) {
+	if name == "PATH" {
+		u.Path = append(u.Path, p)
+		return
+	}
+	if u.Lists == nil {
+		u.Lists = make(map[string][]string)
+	}
+	u.Lists[name] = append(u.Lists[name], p)
+}
+
+// AllLists returns the paths added to all list env vars, including PATH.
+func (u *PathUndo) AllLists() map[string][]string {
+	all := make(map[string][]string)
+	if len(u.Path) > 0 {
+		all["PATH"] = u.Path
+	}
+	for name, l := range u.Lists {
+		all[name] = l
+	}
+	return all
+}
+
+// ClearLists forgets all paths added to list env vars.
+func (u *PathUndo) ClearLists() {
+	u.Path = nil
+	u.Lists = make(map[string][]string)
+	u.PathOrigin = make(map[string]string)
+}
+
 // PathUndoList is a slice of PathUndo
 type PathUndoList []*PathUndo
 
@@ -29,6 +142,29 @@ type PathUndoList []*PathUndo
 type Session struct {
 	Path string
 	Undo map[string]*PathUndo
+	// Disabled is set when envy is suspended with 'envy off'
+	Disabled bool `json:",omitempty"`
+	// Aliases contains the shell aliases currently defined by envy
+	Aliases map[string]string `json:",omitempty"`
+	// Shell is the process ID of the shell the aliases were defined in.
+	// Unlike the session, aliases are not inherited by new shells.
+	Shell int `json:",omitempty"`
+}
+
+// ListPaths returns all paths envy added to list env vars, by var name.
+func (s *Session) ListPaths() map[string]map[string]bool {
+	owned := make(map[string]map[string]bool)
+	for _, u := range s.Undo {
+		for name, l := range u.AllLists() {
+			if owned[name] == nil {
+				owned[name] = make(map[string]bool)
+			}
+			for _, p := range l {
+				owned[name][p] = true
+			}
+		}
+	}
+	return owned
 }
 
 // UndoFor returns the PathUndo for a directory
@@ -65,6 +201,12 @@ func (s *Session) ToUndoFor(p string) PathUndoList {
 	return undo
 }
 
+// UndoAll returns a list of all actions to undo, from deep to shallow path,
+// and removes the items from the session.
+func (s *Session) UndoAll() PathUndoList {
+	return s.ToUndoFor("")
+}
+
 // PathUndoList returns a list of all the current PathUndo instances, sorted
 // by path
 // ToUndoFor returns a list of action to undo for a new working dir, and removes
@@ -1,15 +1,13 @@
 package shell
 
 import (
-	"fmt"
-	"log"
-	"path/filepath"
+	"io"
 	"strings"
 
 	"github.com/wojas/envy/env"
 )
 
-// Bash returns a Shell interface for the bash and zsh shells.
+// Bash returns a Shell interface for the bash shell.
 func Bash() Shell {
 	return bash{}
 }
@@ -24,19 +22,59 @@ func (sh bash) Quote(s string) string {
 	return "'" + strings.Replace(s, "'", `'"'"'`, -1) + "'"
 }
 
-// SetEnv prints a shell env var export command. The key is expected to be
-// safe, the value is escaped.
-func (sh bash) SetEnv(key, value string) {
-	if !ValidEnvVar(key) {
-		// Should have been checked by caller
-		log.Printf("SetEnv got an invalid env var name: %v", key)
-		return
+// Render writes the commands for all changes in the script to w.
+func (sh bash) Render(w io.Writer, script *Script) error {
+	return renderLines(w, sh, script)
+}
+
+// setEnv returns a shell env var export command. The key is expected to be
+// safe, the value is escaped. Every command ends with a ';', so that the
+// output still works when it is passed to eval without quotes, which joins
+// all lines with spaces.
+func (sh bash) setEnv(key, value string) string {
+	return "export " + key + "=" + sh.Quote(value) + ";"
+}
+
+// unsetEnv returns a shell command to remove an env var.
+func (sh bash) unsetEnv(key string) string {
+	return "unset " + key + ";"
+}
+
+// setList returns the shell command to set a new PATH or other list env var.
+// After a PATH change the shell must forget the locations of commands it
+// remembered, or it keeps running the ones found in the old PATH.
+func (sh bash) setList(list *env.List) (string, bool) {
+	cmd := sh.setEnv(list.Name, list.String())
+	if list.Name == "PATH" {
+		cmd += "\nhash -r;"
 	}
-	fmt.Printf("export %s=%s\n", key, sh.Quote(value))
+	return cmd, true
+}
+
+// setAlias returns a shell command to define an alias.
+func (sh bash) setAlias(name, command string) string {
+	return "alias " + name + "=" + sh.Quote(command) + ";"
+}
+
+// unsetAlias returns a shell command to remove an alias, and to restore the
+// definition saved by saveAlias, if any.
+func (sh bash) unsetAlias(name string) string {
+	prev := "_envy_prev_" + name
+	return "unalias " + name + " 2>/dev/null; eval \"$" + prev + "\"; unset " + prev + ";"
+}
+
+// saveAlias returns a shell command to save the current definition of an
+// alias in a shell var. For bash this is an alias command that defines it.
+// A definition that was already saved is kept, because in a subshell the
+// alias may have been defined by envy in the parent shell.
+func (sh bash) saveAlias(name string) (string, bool) {
+	prev := "_envy_prev_" + name
+	return "[ -n \"${" + prev + "+x}\" ] || " + prev + "=$(alias " + name + " 2>/dev/null);", true
 }
 
-// SetPath prints the shell command to set a new path.
-func (sh bash) SetPath(path *env.Path) {
-	pathenv := strings.Join(path.Get(), string(filepath.ListSeparator))
-	sh.SetEnv("PATH", pathenv)
+// guard wraps the commands in a group, which the shell only runs once it has
+// parsed all of it. The closing brace is only recognised after a ';', which
+// ends every command, so it also works when the newlines are lost.
+func (sh bash) guard() (begin, end string) {
+	return "{\n", "}\n"
 }
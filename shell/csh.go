@@ -0,0 +1,76 @@
+package shell
+
+import (
+	"io"
+	"strings"
+
+	"github.com/wojas/envy/env"
+)
+
+// Csh returns a Shell interface for the csh and tcsh shells.
+func Csh() Shell {
+	return csh{}
+}
+
+type csh struct{}
+
+// cshEscapes escapes the characters that are special within single quotes.
+// A ' cannot be escaped, so we exit the quotes and escape it with a
+// backslash. History substitution of ! happens even within single quotes, so
+// it is also escaped outside of them. A newline within quotes must be
+// preceded by a backslash.
+var cshEscapes = strings.NewReplacer(
+	"'", `'\''`,
+	"!", `'\!'`,
+	"\n", "\\\n",
+)
+
+// Quote quotes a value with single quotes in a way that is safe to pass
+// to the shell.
+func (sh csh) Quote(s string) string {
+	return "'" + cshEscapes.Replace(s) + "'"
+}
+
+// Render writes the commands for all changes in the script to w.
+func (sh csh) Render(w io.Writer, script *Script) error {
+	return renderLines(w, sh, script)
+}
+
+// setEnv returns a shell env var setenv command. The key is expected to be
+// safe, the value is escaped.
+func (sh csh) setEnv(key, value string) string {
+	return "setenv " + key + " " + sh.Quote(value)
+}
+
+// unsetEnv returns a shell command to remove an env var.
+func (sh csh) unsetEnv(key string) string {
+	return "unsetenv " + key
+}
+
+// setList returns the shell command to set a new PATH or other list env var.
+// The shell updates its path variable when PATH is set.
+func (sh csh) setList(list *env.List) (string, bool) {
+	return sh.setEnv(list.Name, list.String()), true
+}
+
+// setAlias returns a shell command to define an alias. Arguments are
+// appended to the command by the shell.
+func (sh csh) setAlias(name, command string) string {
+	return "alias " + name + " " + sh.Quote(command)
+}
+
+// unsetAlias returns a shell command to remove an alias.
+func (sh csh) unsetAlias(name string) string {
+	return "unalias " + name
+}
+
+// saveAlias returns false, because restoring aliases is not supported.
+func (sh csh) saveAlias(name string) (string, bool) {
+	return "", false
+}
+
+// guard returns nothing, because csh runs every line as soon as it is read.
+// Instead, the hook only sources the output if envy exited successfully.
+func (sh csh) guard() (begin, end string) {
+	return "", ""
+}
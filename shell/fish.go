@@ -2,7 +2,7 @@ package shell
 
 import (
 	"bytes"
-	"fmt"
+	"io"
 	"log"
 	"strings"
 
@@ -25,38 +25,70 @@ func (sh fish) Quote(s string) string {
 	return "'" + strings.Replace(strings.Replace(s, `\`, `\\`, -1), "'", `\'`, -1) + "'"
 }
 
-// SetEnv prints a shell env var export command. The key is expected to be
+// Render writes the commands for all changes in the script to w.
+func (sh fish) Render(w io.Writer, script *Script) error {
+	return renderLines(w, sh, script)
+}
+
+// setEnv returns a shell env var export command. The key is expected to be
 // safe, the value is escaped.
-func (sh fish) SetEnv(key, value string) {
-	if !ValidEnvVar(key) {
-		// Should have been checked by caller
-		log.Printf("SetEnv got an invalid env var name: %v", key)
-		return
-	}
+func (sh fish) setEnv(key, value string) string {
+	return "set -xg " + key + " " + sh.Quote(value) + ";"
+}
 
-	buf := bytes.NewBuffer(nil)
-	buf.WriteString("set -xg ")
-	buf.WriteString(key)
-	buf.WriteByte(' ')
-	buf.WriteString(sh.Quote(value))
-	buf.WriteByte(';')
-	fmt.Println(buf.String())
+// unsetEnv returns a shell command to remove an env var.
+func (sh fish) unsetEnv(key string) string {
+	return "set -e " + key + ";"
 }
 
-// SetPath prints the shell command to set a new path.
-func (sh fish) SetPath(path *env.Path) {
-	pathlist := path.Get()
-	if len(pathlist) == 0 {
+// setList returns the shell command to set a new PATH or other list env var.
+// Fish treats variables with a name ending in PATH as lists, so these are set
+// to a list of paths. Other lists are set to a ':' separated string.
+func (sh fish) setList(list *env.List) (string, bool) {
+	if !strings.HasSuffix(list.Name, "PATH") {
+		return sh.setEnv(list.Name, list.String()), true
+	}
+
+	pathlist := list.Get()
+	if len(pathlist) == 0 && list.Name == "PATH" {
 		log.Printf("Refusing to set an empty PATH")
-		return
+		return "", false
 	}
 
 	buf := bytes.NewBuffer(nil)
-	buf.WriteString("set -xg PATH")
+	buf.WriteString("set -xg ")
+	buf.WriteString(list.Name)
 	for _, p := range pathlist {
 		buf.WriteByte(' ')
 		buf.WriteString(sh.Quote(p))
 	}
 	buf.WriteByte(';')
-	fmt.Println(buf.String())
+	return buf.String(), true
+}
+
+// setAlias returns a shell command to define a function that runs the
+// command with any arguments appended, like the fish alias command does.
+// The command is shell code, so it is not quoted.
+func (sh fish) setAlias(name, command string) string {
+	return "function " + name + "; " + command + " $argv; end;"
+}
+
+// unsetAlias returns a shell command to remove an alias function, and to
+// restore the function saved by saveAlias, if any.
+func (sh fish) unsetAlias(name string) string {
+	prev := "_envy_prev_" + name
+	return "functions -e " + name + "; echo $" + prev + " | source; set -e " + prev + ";"
+}
+
+// saveAlias returns a shell command to save the current definition of a
+// function in a shell var, unless one was already saved.
+func (sh fish) saveAlias(name string) (string, bool) {
+	prev := "_envy_prev_" + name
+	return "set -q " + prev + "; or set -g " + prev + " (functions " + name + " 2>/dev/null | string join \\n);", true
+}
+
+// guard wraps the commands in a block, which the shell only runs once it has
+// parsed all of it.
+func (sh fish) guard() (begin, end string) {
+	return "begin;\n", "end;\n"
 }
@@ -0,0 +1,86 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+)
+
+// hooks contains the hook code templates for each supported shell. The
+// template gets the quoted path of the envy binary as argument.
+var hooks = map[string]string{
+	// PROMPT_COMMAND can be an array since bash 5.1. The hook preserves $?,
+	// so that the prompt and other PROMPT_COMMAND entries still see it.
+	"bash": `_envy_hook() {
+  local previous_exit_status=$?
+  eval "$(%[1]s -shell=bash session)"
+  return $previous_exit_status
+}
+if [[ ";${PROMPT_COMMAND[*]:-};" != *";_envy_hook;"* ]]; then
+  if [[ "$(declare -p PROMPT_COMMAND 2>/dev/null)" == "declare -a"* ]]; then
+    PROMPT_COMMAND+=(_envy_hook)
+  else
+    PROMPT_COMMAND="${PROMPT_COMMAND:+${PROMPT_COMMAND%%;};}_envy_hook"
+  fi
+fi
+`,
+	// add-zsh-hook does not clobber existing precmd functions. The chpwd hook
+	// makes sure the env is also updated for 'cd dir && cmd'.
+	"zsh": `_envy_hook() {
+  local previous_exit_status=$?
+  eval "$(%[1]s -shell=zsh session)"
+  return $previous_exit_status
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook precmd _envy_hook
+add-zsh-hook chpwd _envy_hook
+`,
+	"fish": `function _envy_hook --on-event fish_prompt
+    %[1]s -shell=fish session | source
+end
+`,
+	// The existing prompt function is wrapped, so that custom prompts keep
+	// working. Running envy changes $LASTEXITCODE, so it is restored.
+	"pwsh": `if (-not $global:_envy_prompt) {
+    $global:_envy_prompt = $function:prompt
+    function global:prompt {
+        $previousExitCode = $global:LASTEXITCODE
+        & %[1]s -shell=pwsh session | Out-String | Invoke-Expression
+        $global:LASTEXITCODE = $previousExitCode
+        & $global:_envy_prompt
+    }
+}
+`,
+	// Env changes made by a hook closure are kept. Existing hooks are kept.
+	"nu": `$env.config = ($env.config | upsert hooks.pre_prompt (
+    $env.config.hooks.pre_prompt? | default [] | append {||
+        let envy = (^%[1]s -shell=nu session | from json)
+        hide-env --ignore-errors ...$envy.hide
+        load-env $envy.load
+    }
+))
+`,
+	// The output is sourced from a file, because a value with a newline does
+	// not survive eval. It is only sourced if envy succeeded, because csh
+	// would run the lines of incomplete output. This replaces any existing
+	// precmd alias. It is a single line, because eval joins the lines of the
+	// hook with spaces.
+	"tcsh": cshHook,
+	"csh":  cshHook,
+}
+
+const cshHook = `set _envy_bin = %[1]s; alias precmd 'set _envy_script = ` + "`mktemp`" + `; "$_envy_bin" -shell=csh session >! "$_envy_script" && source "$_envy_script"; rm -f "$_envy_script"; unset _envy_script'
+`
+
+// Hook returns the code to add to the config of a shell to run envy before
+// every prompt. The bin is the path to the envy binary.
+func Hook(shell, bin string) (string, error) {
+	tmpl, exists := hooks[shell]
+	if !exists {
+		return "", fmt.Errorf("unsupported shell %q, use one of: %s", shell, strings.Join(Names, ", "))
+	}
+	sh, err := New(shell)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(tmpl, sh.Quote(bin)), nil
+}
@@ -0,0 +1,116 @@
+package shell
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/wojas/envy/env"
+)
+
+// JSON returns a Shell interface that renders a single JSON document with all
+// changes, for editor and IDE integrations that cannot evaluate shell code.
+// The Shell also implements io.Writer, so that log messages can be included
+// in the document.
+func JSON() Shell {
+	return &jsonShell{
+		messages: make([]string, 0),
+	}
+}
+
+type jsonList struct {
+	List    []string `json:"list"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+type jsonDoc struct {
+	Set      map[string]string    `json:"set"`
+	Unset    []string             `json:"unset"`
+	Path     *jsonList            `json:"path"`
+	Lists    map[string]jsonList  `json:"lists"`
+	Aliases  map[string]env.Value `json:"aliases"`
+	OnLeave  []string             `json:"on_leave"`
+	OnEnter  []string             `json:"on_enter"`
+	Session  string               `json:"session"`
+	Messages []string             `json:"messages"`
+}
+
+type jsonShell struct {
+	messages []string
+}
+
+// Quote quotes a value as a JSON string.
+func (sh *jsonShell) Quote(s string) string {
+	blob, _ := json.Marshal(s)
+	return string(blob)
+}
+
+// Render writes the JSON document to w. The envy session is stored
+// separately. If the script does not change it, like when envy is suspended,
+// the current session is included, so that the editor can always store the
+// session it gets. For PATH and other list vars the paths that were added and
+// removed are included.
+func (sh *jsonShell) Render(w io.Writer, script *Script) error {
+	doc := jsonDoc{
+		Set:      make(map[string]string),
+		Unset:    make([]string, 0),
+		Lists:    make(map[string]jsonList),
+		Aliases:  make(map[string]env.Value),
+		OnLeave:  make([]string, 0),
+		OnEnter:  make([]string, 0),
+		Session:  os.Getenv(sessionVar),
+		Messages: sh.messages,
+	}
+	for _, op := range script.Ops() {
+		switch {
+		case op.Key == sessionVar:
+			doc.Session = op.Val
+		case op.Kind == OpOnLeave:
+			doc.OnLeave = append(doc.OnLeave, op.Val)
+		case op.Kind == OpOnEnter:
+			doc.OnEnter = append(doc.OnEnter, op.Val)
+		case op.Kind == OpSaveAlias:
+			// The editor has no aliases of its own to restore
+		case op.Kind == OpSetAlias || op.Kind == OpUnsetAlias:
+			doc.Aliases[op.Key] = op.Value() // Removed aliases are null
+		case op.Kind == OpUnset:
+			doc.Unset = append(doc.Unset, op.Key)
+		case op.Kind == OpSet:
+			doc.Set[op.Key] = op.Val
+		case op.Kind == OpSetList:
+			l := jsonList{
+				List:    nonNil(op.List.Get()),
+				Added:   nonNil(op.List.Added()),
+				Removed: nonNil(op.List.Removed()),
+			}
+			if op.Key == "PATH" {
+				doc.Path = &l
+			} else {
+				doc.Lists[op.Key] = l
+			}
+		}
+	}
+	blob, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(blob))
+	return err
+}
+
+// Write implements io.Writer to record log messages. The log package calls
+// it once per message, so a message with multiple lines is kept as one.
+func (sh *jsonShell) Write(p []byte) (int, error) {
+	sh.messages = append(sh.messages, strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+func nonNil(a []string) []string {
+	if a == nil {
+		return make([]string, 0)
+	}
+	return a
+}
@@ -0,0 +1,78 @@
+package shell
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+)
+
+// Nu returns a Shell interface for Nushell. Nushell cannot evaluate code
+// from a string, so all changes are rendered as a single JSON record, which
+// the hook passes to load-env and hide-env.
+func Nu() Shell {
+	return nu{}
+}
+
+// nuDoc contains the vars to set, with PATH as a list, and the vars to remove.
+type nuDoc struct {
+	Load map[string]interface{} `json:"load"`
+	Hide []string               `json:"hide"`
+}
+
+type nu struct{}
+
+// nuEscapes are the backslash escapes supported in double quoted strings.
+var nuEscapes = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+)
+
+// Quote quotes a value with double quotes in a way that is safe to pass
+// to the shell. This is only used for the hook, the changes themselves are
+// passed as JSON.
+func (sh nu) Quote(s string) string {
+	return `"` + nuEscapes.Replace(s) + `"`
+}
+
+// Render writes the JSON record with all changes to w. Nushell converts PATH
+// to a list on startup, so it is set to a list of paths. Other lists are not
+// converted by default, so these are set to a ':' separated string.
+func (sh nu) Render(w io.Writer, script *Script) error {
+	doc := nuDoc{
+		Load: make(map[string]interface{}),
+		Hide: make([]string, 0),
+	}
+	for _, op := range script.Ops() {
+		switch {
+		case op.Kind == OpOnLeave || op.Kind == OpOnEnter:
+			log.Printf("Commands are not supported in nu, ignoring %q", op.Val)
+		case op.Kind == OpSaveAlias:
+			// Nothing to save, because aliases are not supported
+		case op.Kind == OpSetAlias || op.Kind == OpUnsetAlias:
+			// Aliases are resolved when the code is parsed, so these cannot
+			// be changed from a hook.
+			log.Printf("Aliases are not supported in nu, ignoring %s", op.Key)
+		case op.Kind == OpUnset:
+			doc.Hide = append(doc.Hide, op.Key)
+		case op.Kind == OpSetList && op.Key == "PATH":
+			if len(op.List.Get()) == 0 {
+				log.Printf("Refusing to set an empty PATH")
+				continue
+			}
+			doc.Load[op.Key] = op.List.Get()
+		default:
+			doc.Load[op.Key] = op.Value().Val
+		}
+	}
+	blob, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(blob))
+	return err
+}
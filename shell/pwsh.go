@@ -0,0 +1,81 @@
+package shell
+
+import (
+	"io"
+	"strings"
+
+	"github.com/wojas/envy/env"
+)
+
+// Pwsh returns a Shell interface for PowerShell.
+func Pwsh() Shell {
+	return pwsh{}
+}
+
+type pwsh struct{}
+
+// pwshQuotes are the characters PowerShell treats as a single quote. Besides
+// the ASCII quote, this includes several typographic quotes.
+var pwshQuotes = strings.NewReplacer(
+	"'", "''",
+	"‘", "‘‘",
+	"’", "’’",
+	"‚", "‚‚",
+	"‛", "‛‛",
+)
+
+// Quote quotes a value with single quotes in a way that is safe to pass
+// to the shell. Within single quotes, the only special character is the quote
+// itself, which is escaped by doubling it.
+func (sh pwsh) Quote(s string) string {
+	return "'" + pwshQuotes.Replace(s) + "'"
+}
+
+// Render writes the commands for all changes in the script to w.
+func (sh pwsh) Render(w io.Writer, script *Script) error {
+	return renderLines(w, sh, script)
+}
+
+// setEnv returns a shell env var assignment. The key is expected to be
+// safe, the value is escaped.
+func (sh pwsh) setEnv(key, value string) string {
+	return "$env:" + key + " = " + sh.Quote(value)
+}
+
+// unsetEnv returns a shell command to remove an env var.
+func (sh pwsh) unsetEnv(key string) string {
+	return "Remove-Item Env:" + key + " -ErrorAction SilentlyContinue"
+}
+
+// setList returns the shell command to set a new PATH or other list env var.
+// The paths are joined with the path separator of the platform.
+func (sh pwsh) setList(list *env.List) (string, bool) {
+	quoted := make([]string, 0, len(list.Get()))
+	for _, p := range list.Get() {
+		quoted = append(quoted, sh.Quote(p))
+	}
+	return "$env:" + list.Name + " = @(" + strings.Join(quoted, ", ") + ") -join [IO.Path]::PathSeparator", true
+}
+
+// setAlias returns a shell command to define a global function that runs
+// the command with any arguments appended, because a PowerShell alias cannot
+// include arguments. The command is shell code, so it is not quoted.
+func (sh pwsh) setAlias(name, command string) string {
+	return "function global:" + name + " { " + command + " @args }"
+}
+
+// unsetAlias returns a shell command to remove an alias function.
+func (sh pwsh) unsetAlias(name string) string {
+	return "Remove-Item Function:" + name + " -ErrorAction SilentlyContinue"
+}
+
+// saveAlias returns false, because restoring aliases is not supported.
+func (sh pwsh) saveAlias(name string) (string, bool) {
+	return "", false
+}
+
+// guard wraps the commands in a script block that is dot sourced, so that it
+// runs in the current scope, but only once the shell has parsed all of it.
+func (sh pwsh) guard() (begin, end string) {
+	return ". {\n", "}\n"
+}
@@ -0,0 +1,214 @@
+package shell
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/wojas/envy/env"
+)
+
+// sessionVar is the env var that stores the envy session.
+const sessionVar = "_envy_session"
+
+// OpKind is the kind of change an Op makes. The kinds are listed in the order
+// in which Ops returns them.
+type OpKind int
+
+const (
+	// OpOnLeave runs a command for a directory that is no longer active,
+	// before its changes are undone
+	OpOnLeave OpKind = iota
+	// OpUnset removes an env var
+	OpUnset
+	// OpSet sets an env var to a value
+	OpSet
+	// OpSetList sets PATH or another list env var
+	OpSetList
+	// OpUnsetAlias removes a shell alias, and restores the alias that was
+	// saved before it was defined
+	OpUnsetAlias
+	// OpSaveAlias saves the alias the user defined, before it is replaced
+	OpSaveAlias
+	// OpSetAlias defines a shell alias that runs a command
+	OpSetAlias
+	// OpOnEnter runs a command for a directory that became active, after all
+	// changes were made
+	OpOnEnter
+)
+
+// Op is a single change to the environment.
+type Op struct {
+	Kind OpKind
+	Key  string
+	Val  string    // For OpSet, OpSetAlias and the commands to run
+	List *env.List // For OpSetList
+}
+
+// Value returns the value of the env var or alias after the change.
+func (op Op) Value() env.Value {
+	switch op.Kind {
+	case OpUnset, OpUnsetAlias:
+		return env.Value{Unset: true}
+	case OpSetList:
+		return env.Value{Val: op.List.String()}
+	default:
+		return env.Value{Val: op.Val}
+	}
+}
+
+// Script collects all changes to the environment, so that a Shell can render
+// them at once and in a defined order.
+type Script struct {
+	ops []Op
+}
+
+// NewScript returns a new empty Script.
+func NewScript() *Script {
+	return &Script{}
+}
+
+// SetEnv adds a change that sets an env var.
+func (s *Script) SetEnv(key, value string) {
+	s.ops = append(s.ops, Op{Kind: OpSet, Key: key, Val: value})
+}
+
+// UnsetEnv adds a change that removes an env var.
+func (s *Script) UnsetEnv(key string) {
+	s.ops = append(s.ops, Op{Kind: OpUnset, Key: key})
+}
+
+// SetList adds a change that sets PATH or another list env var.
+func (s *Script) SetList(list *env.List) {
+	s.ops = append(s.ops, Op{Kind: OpSetList, Key: list.Name, List: list})
+}
+
+// SetAlias adds a change that defines a shell alias.
+func (s *Script) SetAlias(name, command string) {
+	s.ops = append(s.ops, Op{Kind: OpSetAlias, Key: name, Val: command})
+}
+
+// UnsetAlias adds a change that removes a shell alias, and restores the alias
+// saved with SaveAlias, if any.
+func (s *Script) UnsetAlias(name string) {
+	s.ops = append(s.ops, Op{Kind: OpUnsetAlias, Key: name})
+}
+
+// SaveAlias adds a change that saves the current definition of an alias in
+// the shell, so that UnsetAlias can restore it after envy replaced it.
+func (s *Script) SaveAlias(name string) {
+	s.ops = append(s.ops, Op{Kind: OpSaveAlias, Key: name})
+}
+
+// OnLeave adds a command to run for a directory that is no longer active.
+func (s *Script) OnLeave(command string) {
+	s.ops = append(s.ops, Op{Kind: OpOnLeave, Val: command})
+}
+
+// OnEnter adds a command to run for a directory that became active.
+func (s *Script) OnEnter(command string) {
+	s.ops = append(s.ops, Op{Kind: OpOnEnter, Val: command})
+}
+
+// Ops returns all changes in the order in which they must be applied: first
+// the commands for directories we left, then the unsets, the sets, the lists,
+// the aliases and the commands for directories we entered. Aliases are saved
+// before any alias is defined. Changes are sorted
+// by key, the commands keep the order in which they were added. The envy
+// session always comes last, so that it is only updated if all other changes
+// were applied.
+func (s *Script) Ops() []Op {
+	ops := make([]Op, len(s.ops))
+	copy(ops, s.ops)
+	sort.SliceStable(ops, func(i, j int) bool {
+		a, b := ops[i], ops[j]
+		if (a.Key == sessionVar) != (b.Key == sessionVar) {
+			return b.Key == sessionVar
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Key < b.Key
+	})
+	return ops
+}
+
+// lineShell is implemented by shells that take a command per change.
+type lineShell interface {
+	setEnv(key, value string) string
+	unsetEnv(key string) string
+	// setList returns false if the list cannot be set
+	setList(list *env.List) (string, bool)
+	setAlias(name, command string) string
+	unsetAlias(name string) string
+	// saveAlias returns false if the shell cannot restore aliases
+	saveAlias(name string) (string, bool)
+	// guard returns the lines to wrap the commands in, so that the shell
+	// does not run any of them if the output is incomplete.
+	guard() (begin, end string)
+}
+
+// renderLines writes the commands for all changes in the script to w in a
+// single write. Nothing is written if there are no changes.
+func renderLines(w io.Writer, sh lineShell, script *Script) error {
+	ops := script.Ops()
+	if len(ops) == 0 {
+		return nil
+	}
+
+	begin, end := sh.guard()
+	buf := bytes.NewBufferString(begin)
+	for _, op := range ops {
+		if op.Kind == OpOnLeave || op.Kind == OpOnEnter {
+			// Shell code written by the user, so it is used as is
+			buf.WriteString(endCommand(op.Val))
+			buf.WriteByte('\n')
+			continue
+		}
+		if !ValidEnvVar(op.Key) {
+			// Should have been checked by caller
+			log.Printf("Got an invalid env var name: %v", op.Key)
+			continue
+		}
+		var line string
+		switch op.Kind {
+		case OpUnset:
+			line = sh.unsetEnv(op.Key)
+		case OpSet:
+			line = sh.setEnv(op.Key, op.Val)
+		case OpSetList:
+			var ok bool
+			if line, ok = sh.setList(op.List); !ok {
+				continue
+			}
+		case OpUnsetAlias:
+			line = sh.unsetAlias(op.Key)
+		case OpSaveAlias:
+			var ok bool
+			if line, ok = sh.saveAlias(op.Key); !ok {
+				continue
+			}
+		case OpSetAlias:
+			line = sh.setAlias(op.Key, op.Val)
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteString(end)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// endCommand terminates a command written by the user with a ';', so that
+// the next command is not appended to it when the shell joins all lines with
+// spaces. Commands that already end in a ';' or '&' are kept as they are,
+// because the shells reject an empty command after them.
+func endCommand(cmd string) string {
+	cmd = strings.TrimRight(cmd, " \t\n")
+	if strings.HasSuffix(cmd, ";") || strings.HasSuffix(cmd, "&") {
+		return cmd
+	}
+	return cmd + ";"
+}
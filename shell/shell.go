@@ -1,12 +1,13 @@
 package shell
 
 import (
+	"fmt"
+	"io"
 	"regexp"
-
-	"github.com/wojas/envy/env"
+	"strings"
 )
 
-var valid = regexp.MustCompile(`[a-zA-Z_]+[a-zA-Z0-9_]*`)
+var valid = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
 
 // ValidEnvVar checks if an environment variable name is valid.
 // https://stackoverflow.com/questions/2821043/
@@ -17,6 +18,29 @@ func ValidEnvVar(key string) bool {
 // Shell defines the interface that shell support modules must implement.
 type Shell interface {
 	Quote(s string) string
-	SetEnv(key, value string)
-	SetPath(path *env.Path)
+	// Render writes the commands for all changes in the script to w
+	Render(w io.Writer, script *Script) error
+}
+
+// Names lists the names of all supported shells.
+var Names = []string{"bash", "zsh", "fish", "pwsh", "nu", "tcsh", "csh"}
+
+// New returns the Shell for a shell name.
+func New(name string) (Shell, error) {
+	switch name {
+	case "bash":
+		return Bash(), nil
+	case "zsh":
+		return Zsh(), nil
+	case "fish":
+		return Fish(), nil
+	case "pwsh":
+		return Pwsh(), nil
+	case "nu":
+		return Nu(), nil
+	case "tcsh", "csh":
+		return Csh(), nil
+	default:
+		return nil, fmt.Errorf("unsupported shell %q, use one of: %s", name, strings.Join(Names, ", "))
+	}
 }
@@ -0,0 +1,40 @@
+package shell
+
+import (
+	"io"
+
+	"github.com/wojas/envy/env"
+)
+
+// Zsh returns a Shell interface for the zsh shell. It only differs from bash
+// in how it clears the command hash table.
+func Zsh() Shell {
+	return zsh{}
+}
+
+type zsh struct {
+	bash
+}
+
+// Render writes the commands for all changes in the script to w.
+func (sh zsh) Render(w io.Writer, script *Script) error {
+	return renderLines(w, sh, script)
+}
+
+// saveAlias returns a shell command to save the current definition of an
+// alias in a shell var. Unlike bash, zsh only prints the alias command for
+// it with -L.
+func (sh zsh) saveAlias(name string) (string, bool) {
+	prev := "_envy_prev_" + name
+	return "[ -n \"${" + prev + "+x}\" ] || " + prev + "=$(alias -L " + name + " 2>/dev/null);", true
+}
+
+// setList returns the shell command to set a new PATH or other list env var,
+// followed by a rehash after a PATH change.
+func (sh zsh) setList(list *env.List) (string, bool) {
+	cmd := sh.setEnv(list.Name, list.String())
+	if list.Name == "PATH" {
+		cmd += "\nrehash;"
+	}
+	return cmd, true
+}
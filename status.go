@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/wojas/envy/paths"
+	"github.com/wojas/envy/session"
+)
+
+// StatusVar describes a var changed by envy for a directory. Values are nil
+// if the var is unset.
+type StatusVar struct {
+	Key    string  `json:"key"`
+	Old    *string `json:"old"`
+	New    *string `json:"new"`
+	Origin string  `json:"origin,omitempty"`
+}
+
+// StatusPath describes a path added by envy to PATH or another list var.
+type StatusPath struct {
+	List   string `json:"list"`
+	Path   string `json:"path"`
+	Origin string `json:"origin,omitempty"`
+}
+
+// StatusDir describes the changes for a single active directory.
+type StatusDir struct {
+	Dir   string       `json:"dir"`
+	Vars  []StatusVar  `json:"vars"`
+	Paths []StatusPath `json:"paths"`
+}
+
+// Status describes the current envy session.
+type Status struct {
+	Path     string      `json:"path"`
+	Disabled bool        `json:"disabled"`
+	Dirs     []StatusDir `json:"dirs"`
+}
+
+// getStatus collects the status from a session, with all active directories
+// sorted from shallow to deep.
+func getStatus(ses *session.Session) Status {
+	st := Status{
+		Path:     ses.Path,
+		Disabled: ses.Disabled,
+		Dirs:     make([]StatusDir, 0),
+	}
+
+	dirs := make([]string, 0, len(ses.Undo))
+	for dir := range ses.Undo {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	for i, dir := range dirs {
+		u := ses.Undo[dir]
+		sd := StatusDir{
+			Dir:   dir,
+			Vars:  make([]StatusVar, 0),
+			Paths: make([]StatusPath, 0),
+		}
+
+		keys := make([]string, 0, len(u.Env))
+		for k := range u.Env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			sv := StatusVar{
+				Key:    k,
+				Origin: u.Origin[k],
+			}
+			if old := u.Env[k]; !old.Unset {
+				sv.Old = &old.Val
+			}
+			sv.New = newValue(ses, dirs[i+1:], k)
+			sd.Vars = append(sd.Vars, sv)
+		}
+
+		lists := u.AllLists()
+		names := make([]string, 0, len(lists))
+		for name := range lists {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, p := range lists[name] {
+				sd.Paths = append(sd.Paths, StatusPath{
+					List:   name,
+					Path:   p,
+					Origin: u.PathOrigin[p],
+				})
+			}
+		}
+
+		st.Dirs = append(st.Dirs, sd)
+	}
+	return st
+}
+
+// newValue returns the value a directory set a var to. If a deeper directory
+// changed it again, the value is the one that directory stored for undo.
+func newValue(ses *session.Session, deeper []string, key string) *string {
+	for _, dir := range deeper {
+		if v, exists := ses.Undo[dir].Env[key]; exists {
+			if v.Unset {
+				return nil
+			}
+			return &v.Val
+		}
+	}
+	if v, set := os.LookupEnv(key); set {
+		return &v
+	}
+	return nil
+}
+
+// runStatus prints what envy changed for the current session.
+func runStatus(args []string) {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Output the status as JSON")
+	_ = fs.Parse(args)
+
+	ses := session.Load(os.Getenv("_envy_session"))
+	st := getStatus(ses)
+
+	if *jsonOutput {
+		blob, err := json.MarshalIndent(st, "", "  ")
+		if err != nil {
+			log.Fatalf("Cannot marshal status: %v", err)
+		}
+		fmt.Println(string(blob))
+		return
+	}
+
+	home, _ := paths.HomeDir()
+	shorten := paths.Shorten{Home: home}
+	if st.Disabled {
+		fmt.Println("Disabled, run 'envy on' to enable")
+		return
+	}
+	if len(st.Dirs) == 0 {
+		fmt.Println("No active directories")
+		return
+	}
+	for _, sd := range st.Dirs {
+		fmt.Println(shorten.Do(sd.Dir))
+		for _, sv := range sd.Vars {
+			var line string
+			if sv.New == nil {
+				line = fmt.Sprintf("unset %s", sv.Key)
+			} else {
+				line = fmt.Sprintf("%s = %q", sv.Key, *sv.New)
+			}
+			if sv.Old == nil {
+				line += " (was unset)"
+			} else {
+				line += fmt.Sprintf(" (was %q)", *sv.Old)
+			}
+			printStatusLine(line, sv.Origin)
+		}
+		for _, sp := range sd.Paths {
+			printStatusLine(fmt.Sprintf("%s += %s", sp.List, shorten.Do(sp.Path)), sp.Origin)
+		}
+	}
+}
+
+func printStatusLine(line, origin string) {
+	if origin != "" {
+		line += "  [" + origin + "]"
+	}
+	fmt.Println("  " + line)
+}
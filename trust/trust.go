@@ -0,0 +1,172 @@
+package trust
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/wojas/envy/paths"
+)
+
+// Status describes the trust status of a file.
+type Status int
+
+const (
+	// Unknown means the file was never allowed (or was denied).
+	Unknown Status = iota
+	// Modified means the file was allowed, but its contents changed since.
+	Modified
+	// Trusted means the file was allowed with its current contents.
+	Trusted
+)
+
+func (s Status) String() string {
+	switch s {
+	case Trusted:
+		return "trusted"
+	case Modified:
+		return "modified"
+	default:
+		return "unknown"
+	}
+}
+
+// Store keeps track of files the user explicitly allowed. Every allowed file
+// is recorded in a separate file in Dir, named after the SHA-256 of its path,
+// and containing the SHA-256 of the allowed contents and the path, in the
+// same format as sha256sum.
+type Store struct {
+	Dir string
+}
+
+// Entry describes a single allowed file.
+type Entry struct {
+	Path string
+	Hash string
+}
+
+// DefaultDir returns the default trust store directory, which is
+// $XDG_DATA_HOME/envy/allow or ~/.local/share/envy/allow.
+func DefaultDir() (string, error) {
+	if data := os.Getenv("XDG_DATA_HOME"); data != "" {
+		return filepath.Join(data, "envy", "allow"), nil
+	}
+	home, err := paths.HomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "envy", "allow"), nil
+}
+
+// Default returns a Store using the DefaultDir. If that cannot be determined,
+// the Store will not trust any file.
+func Default() *Store {
+	dir, _ := DefaultDir()
+	return &Store{Dir: dir}
+}
+
+// Hash returns the hex encoded SHA-256 of the contents.
+func Hash(contents []byte) string {
+	sum := sha256.Sum256(contents)
+	return hex.EncodeToString(sum[:])
+}
+
+// entryPath returns the path of the store file for an allowed file.
+func (s *Store) entryPath(fpath string) string {
+	return filepath.Join(s.Dir, Hash([]byte(fpath)))
+}
+
+// Allow marks the file at fpath with the given contents as trusted.
+func (s *Store) Allow(fpath string, contents []byte) error {
+	if s.Dir == "" {
+		return fmt.Errorf("no trust store directory")
+	}
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+	line := fmt.Sprintf("%s  %s\n", Hash(contents), fpath)
+	return ioutil.WriteFile(s.entryPath(fpath), []byte(line), 0600)
+}
+
+// Deny removes the trust for the file at fpath. It is not an error if the
+// file was not trusted.
+func (s *Store) Deny(fpath string) error {
+	if s.Dir == "" {
+		return nil
+	}
+	err := os.Remove(s.entryPath(fpath))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Status checks if the file at fpath with the given contents is trusted.
+func (s *Store) Status(fpath string, contents []byte) Status {
+	if s.Dir == "" {
+		return Unknown
+	}
+	data, err := ioutil.ReadFile(s.entryPath(fpath))
+	if err != nil {
+		return Unknown
+	}
+	e, ok := parseEntry(data)
+	if !ok || e.Path != fpath {
+		return Unknown
+	}
+	if e.Hash != Hash(contents) {
+		return Modified
+	}
+	return Trusted
+}
+
+// List returns all allowed files, sorted by path.
+func (s *Store) List() ([]Entry, error) {
+	var entries []Entry
+	if s.Dir == "" {
+		return entries, nil
+	}
+	files, err := ioutil.ReadDir(s.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return nil, err
+	}
+	for _, fi := range files {
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(s.Dir, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if e, ok := parseEntry(data); ok {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	return entries, nil
+}
+
+// parseEntry parses the contents of a store file.
+func parseEntry(data []byte) (e Entry, ok bool) {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	if !sc.Scan() {
+		return e, false
+	}
+	fields := strings.SplitN(sc.Text(), "  ", 2)
+	if len(fields) != 2 || fields[0] == "" || fields[1] == "" {
+		return e, false
+	}
+	return Entry{Path: fields[1], Hash: fields[0]}, true
+}